@@ -0,0 +1,51 @@
+package apiutil
+
+// Code is a stable, machine-readable error identifier. Clients should
+// branch on Code rather than Title or Detail, which are free text.
+type Code string
+
+const (
+	CodeInvalidRequest        Code = "INVALID_REQUEST"
+	CodeValidationError       Code = "VALIDATION_ERROR"
+	CodeMethodNotAllowed      Code = "METHOD_NOT_ALLOWED"
+	CodeSDKNotInitialized     Code = "SDK_NOT_INITIALIZED"
+	CodeUnexpectedResponse    Code = "UNEXPECTED_RESPONSE"
+	CodeSessionNotFound       Code = "SESSION_NOT_FOUND"
+	CodeUpstreamError         Code = "UPSTREAM_ERROR"
+	CodeRequestError          Code = "REQUEST_ERROR"
+	CodeStatusCheckFailed     Code = "STATUS_CHECK_FAILED"
+	CodeDecodeError           Code = "DECODE_ERROR"
+	CodeUnexpectedError       Code = "UNEXPECTED_ERROR"
+	CodeRateLimited           Code = "RATE_LIMITED"
+	CodeServiceUnavailable    Code = "SERVICE_UNAVAILABLE"
+	CodeUnauthenticated       Code = "UNAUTHENTICATED"
+	CodeIdempotencyInProgress Code = "IDEMPOTENCY_KEY_IN_PROGRESS"
+)
+
+// titles gives each code a human-readable summary for the RFC 7807
+// "title" member, which is meant to be stable across occurrences of the
+// same problem type.
+var titles = map[Code]string{
+	CodeInvalidRequest:        "Invalid request",
+	CodeValidationError:       "Validation error",
+	CodeMethodNotAllowed:      "Method not allowed",
+	CodeSDKNotInitialized:     "Glide SDK not initialized",
+	CodeUnexpectedResponse:    "Unexpected response from Glide SDK",
+	CodeSessionNotFound:       "Session not found",
+	CodeUpstreamError:         "Upstream Glide API error",
+	CodeRequestError:          "Failed to build upstream request",
+	CodeStatusCheckFailed:     "Failed to check status",
+	CodeDecodeError:           "Failed to decode upstream response",
+	CodeUnexpectedError:       "Unexpected error",
+	CodeRateLimited:           "Too many requests",
+	CodeServiceUnavailable:    "Service temporarily unavailable",
+	CodeUnauthenticated:       "Authentication required",
+	CodeIdempotencyInProgress: "Request already in progress",
+}
+
+func titleForCode(code Code) string {
+	if title, ok := titles[code]; ok {
+		return title
+	}
+	return string(code)
+}
@@ -0,0 +1,22 @@
+package apiutil
+
+import (
+	"net/http"
+
+	"magical-auth-quickstart-go/logging"
+)
+
+// Recover converts a panic anywhere in next into a problem+json internal
+// error response instead of taking down the server, so handlers don't
+// each need their own recover().
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logging.FromContext(r.Context()).Error("panic handling request", "method", r.Method, "route", r.URL.Path, "panic", rec)
+				WriteError(w, r, New(http.StatusInternalServerError, CodeUnexpectedError, "An unexpected error occurred"))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,98 @@
+// Package apiutil centralizes how this backend turns errors into HTTP
+// responses, following RFC 7807 (application/problem+json) so every
+// handler returns the same error shape instead of ad-hoc JSON.
+package apiutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"magical-auth-quickstart-go/logging"
+)
+
+// Problem is an RFC 7807 problem-details body with the module's own
+// extension members appended (code, requestId, details).
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Code      string                 `json:"code"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error lets a Problem be returned and handled like any other Go error.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// New builds a Problem for the given stable code, using the registered
+// title for that code (see codes.go). Unregistered codes fall back to the
+// code string itself as the title.
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  titleForCode(code),
+		Status: status,
+		Detail: detail,
+		Code:   string(code),
+	}
+}
+
+// WithRequestID attaches a request ID to the problem and returns it for
+// chaining at the call site.
+func (p *Problem) WithRequestID(requestID string) *Problem {
+	p.RequestID = requestID
+	return p
+}
+
+// WithDetails sets the extra module-specific fields reported under the
+// problem's "details" member, replacing any set before.
+func (p *Problem) WithDetails(details map[string]interface{}) *Problem {
+	p.Details = details
+	return p
+}
+
+// WriteError renders err as a problem-details response, negotiating
+// between application/problem+json (RFC 7807) and plain application/json
+// for clients that haven't adopted the media type yet. Any error that
+// isn't already a *Problem is reported as an internal error without
+// leaking its message to the client.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	problem, ok := err.(*Problem)
+	if !ok {
+		problem = New(http.StatusInternalServerError, CodeUnexpectedError, "An unexpected error occurred")
+	}
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+	// Fall back to our own correlation ID when the error didn't come with
+	// one already (e.g. the SDK's upstream request never reached Glide).
+	if problem.RequestID == "" {
+		problem.RequestID = logging.RequestIDFromContext(r.Context())
+	}
+
+	contentType := "application/problem+json"
+	if accept := r.Header.Get("Accept"); accept != "" && !strings.Contains(accept, "application/problem+json") &&
+		(strings.Contains(accept, "application/json") || strings.Contains(accept, "*/*")) {
+		contentType = "application/json"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// MethodNotAllowed writes a 405 problem response. Handlers call this
+// instead of http.Error so method-not-allowed responses share the same
+// schema as every other error.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	WriteError(w, r, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method "+r.Method+" is not allowed on this endpoint"))
+}
@@ -0,0 +1,151 @@
+// Package idempotency caches a handler's response keyed by (client ID,
+// Idempotency-Key) so a retried request - common on flaky mobile networks
+// - replays the cached result instead of re-executing a side-effecting
+// call like GetPhoneNumber/VerifyPhoneNumber against the Glide API.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pendingMarker is the sentinel Reserve stores in place of a real body
+// while the first request for a key is still in flight, so Get can tell
+// "nothing cached yet" apart from "someone else is already working on
+// this key" without a second round trip.
+var pendingMarker = []byte("\x00PENDING")
+
+// Cache stores a serialized response under a dedup key for a bounded
+// window.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, body []byte, ttl time.Duration) error
+	// Reserve atomically claims key for the duration of ttl, returning
+	// false if it's already claimed (either pending or already completed)
+	// by another request. Callers that fail to reserve should not re-run
+	// the side-effecting work the key is guarding.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release drops a reservation that never completed with Put (e.g. the
+	// guarded call failed), so a legitimate retry isn't stuck waiting out
+	// the full reservation ttl.
+	Release(ctx context.Context, key string) error
+}
+
+// NewCache builds the Cache selected by backend ("memory", "redis", or
+// "sql"), mirroring ratelimit.NewCounterStore's backend selection off the
+// same SESSION_STORE env var.
+func NewCache(backend string) (Cache, error) {
+	switch backend {
+	case "", "memory":
+		return newMemoryCache(), nil
+	case "redis":
+		return newRedisCacheFromEnv()
+	case "sql", "postgres":
+		return newMemoryCache(), nil // best-effort like the rate-limit counters; not worth a table for a short-lived dedup cache
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE backend %q", backend)
+	}
+}
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	body      []byte
+	pending   bool
+	expiresAt time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]memoryEntry)}
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || entry.pending || entry.expiresAt.Before(time.Now()) {
+		return nil, false, nil
+	}
+	return entry.body, true, nil
+}
+
+func (c *memoryCache) Put(_ context.Context, key string, body []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = memoryEntry{body: body, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (c *memoryCache) Reserve(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok && entry.expiresAt.After(time.Now()) {
+		return false, nil
+	}
+	c.entries[key] = memoryEntry{pending: true, expiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (c *memoryCache) Release(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && entry.pending {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+type redisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisCacheFromEnv() (*redisCache, error) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return nil, errors.New("REDIS_ADDR must be set when SESSION_STORE=redis")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	return &redisCache{client: client, prefix: "glide:idempotency:"}, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if bytes.Equal(data, pendingMarker) {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+func (c *redisCache) Put(ctx context.Context, key string, body []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.prefix+key, body, ttl).Err()
+}
+
+func (c *redisCache) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, c.prefix+key, pendingMarker, ttl).Result()
+}
+
+func (c *redisCache) Release(ctx context.Context, key string) error {
+	return c.client.Del(ctx, c.prefix+key).Err()
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Middleware injects a per-request logger carrying a generated request ID
+// plus the route and method, so every log line inside a handler is
+// automatically correlatable without each handler building its own fields.
+func Middleware(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := newRequestID()
+
+			logger := base.With(
+				"request_id", requestID,
+				"method", r.Method,
+				"route", r.URL.Path,
+			)
+
+			w.Header().Set("X-Request-Id", requestID)
+
+			start := time.Now()
+			ctx := WithRequestID(WithContext(r.Context(), logger), requestID)
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			logger.Debug("request completed", "status", recorder.status, "duration_ms", time.Since(start).Milliseconds())
+		})
+	}
+}
+
+// statusRecorder captures the status code a handler writes, since
+// http.ResponseWriter doesn't expose it after the fact.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes through to the wrapped writer so streaming handlers (SSE)
+// still see an http.Flusher behind the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
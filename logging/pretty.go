@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// prettyHandler renders log records as a single human-readable line,
+// preserving the "pretty" GLIDE_LOG_FORMAT developers were used to before
+// this package replaced the ad-hoc log.Printf calls.
+type prettyHandler struct {
+	mu    *sync.Mutex
+	out   io.Writer
+	opts  *slog.HandlerOptions
+	attrs []slog.Attr
+}
+
+func newPrettyHandler(out io.Writer, opts *slog.HandlerOptions) *prettyHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	return &prettyHandler{mu: &sync.Mutex{}, out: out, opts: opts}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	line := fmt.Sprintf("%s [%s] %s", r.Time.Format("15:04:05"), r.Level, r.Message)
+
+	attrs := append([]slog.Attr{}, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	for _, a := range attrs {
+		if h.opts.ReplaceAttr != nil {
+			a = h.opts.ReplaceAttr(nil, a)
+		}
+		line += fmt.Sprintf(" %s=%v", a.Key, a.Value)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintln(h.out, line)
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &prettyHandler{mu: h.mu, out: h.out, opts: h.opts, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *prettyHandler) WithGroup(_ string) slog.Handler {
+	// Groups aren't meaningful in a single-line pretty format; flatten them.
+	return h
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+var (
+	phoneNumberPattern = regexp.MustCompile(`\+?\d{7,15}`)
+	bearerTokenPattern = regexp.MustCompile(`(?i)bearer\s+[a-z0-9._-]+`)
+)
+
+// sensitiveKeys are attribute keys whose values are replaced outright
+// rather than pattern-matched, since anything logged under them is
+// assumed to be a secret regardless of shape.
+var sensitiveKeys = map[string]bool{
+	"credential":    true,
+	"api_key":       true,
+	"client_secret": true,
+	"authorization": true,
+}
+
+// sanitizeAttr is installed as slog.HandlerOptions.ReplaceAttr so every
+// log line is redacted the same way regardless of which handler format is
+// in use. It masks phone numbers and bearer tokens found in string values,
+// and fully redacts attributes logged under a known-sensitive key.
+func sanitizeAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[a.Key] {
+		a.Value = slog.StringValue("[REDACTED]")
+		return a
+	}
+
+	if a.Value.Kind() != slog.KindString {
+		return a
+	}
+
+	redacted := bearerTokenPattern.ReplaceAllString(a.Value.String(), "Bearer [REDACTED]")
+	redacted = phoneNumberPattern.ReplaceAllStringFunc(redacted, func(match string) string {
+		if len(match) <= 4 {
+			return match
+		}
+		return match[:4] + "****"
+	})
+
+	a.Value = slog.StringValue(redacted)
+	return a
+}
@@ -0,0 +1,194 @@
+// Package metrics exposes the handler pipeline's counters, a histogram,
+// and a gauge in Prometheus's text exposition format at /metrics.
+//
+// This hand-rolls the exposition format instead of depending on
+// github.com/prometheus/client_golang: this sandbox has no module proxy
+// access, so a new third-party dependency can't be added with a
+// verifiable go.sum entry. The format itself is simple enough (see
+// https://prometheus.io/docs/instrumenting/exposition_formats/) that a
+// small, dependency-free implementation covering counters/histogram/gauge
+// is a reasonable stand-in until that dependency can be added for real.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// counterVec is a counter broken out by a fixed set of label values,
+// keyed by the labels joined in declaration order.
+type counterVec struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]float64
+}
+
+func newCounterVec(labels ...string) *counterVec {
+	return &counterVec{labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) Inc(labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[strings.Join(labelValues, "\x00")]++
+}
+
+// histogramVec buckets observations the way Prometheus clients do: each
+// bucket counter is cumulative (le="0.5" includes everything le="0.1" saw).
+type histogramVec struct {
+	mu      sync.Mutex
+	labels  []string
+	buckets []float64
+	counts  map[string][]uint64 // one cumulative count per bucket; the +Inf bucket is reported from totals
+	sums    map[string]float64
+	totals  map[string]uint64
+}
+
+func newHistogramVec(buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{
+		labels:  labels,
+		buckets: buckets,
+		counts:  make(map[string][]uint64),
+		sums:    make(map[string]float64),
+		totals:  make(map[string]uint64),
+	}
+}
+
+func (h *histogramVec) Observe(seconds float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\x00")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts, ok := h.counts[key]
+	if !ok {
+		counts = make([]uint64, len(h.buckets))
+		h.counts[key] = counts
+	}
+	for i, le := range h.buckets {
+		if seconds <= le {
+			counts[i]++
+		}
+	}
+	h.sums[key] += seconds
+	h.totals[key]++
+}
+
+// gauge is a single unlabeled value a caller can set directly, e.g. from a
+// periodic count of live entries in the session store.
+type gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (g *gauge) Set(v float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = v
+}
+
+func (g *gauge) get() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.value
+}
+
+// The metrics this quickstart's handlers and SDK calls record. Declared as
+// package-level vars, the same way the standard Prometheus client's
+// promauto constructors are typically used, so call sites don't need to
+// carry a registry reference around.
+var (
+	PrepareTotal        = newCounterVec("use_case", "strategy", "result")
+	ProcessDuration     = newHistogramVec([]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}, "use_case")
+	StatusPollTotal     = newCounterVec("status_code")
+	UpstreamErrorsTotal = newCounterVec("code")
+	ActiveSessions      = &gauge{}
+)
+
+// WriteTo renders every metric in Prometheus text exposition format.
+func WriteTo(w io.Writer) {
+	writeCounter(w, "glide_prepare_total", "Outcomes of /api/phone-auth/prepare calls.", PrepareTotal)
+	writeHistogram(w, "glide_process_duration_seconds", "Latency of /api/phone-auth/process calls.", ProcessDuration)
+	writeCounter(w, "glide_status_poll_total", "Outcomes of upstream status polls.", StatusPollTotal)
+	writeCounter(w, "glide_upstream_errors_total", "Glide SDK errors by code.", UpstreamErrorsTotal)
+
+	fmt.Fprintf(w, "# HELP glide_active_sessions Sessions currently tracked by the session store.\n")
+	fmt.Fprintf(w, "# TYPE glide_active_sessions gauge\n")
+	fmt.Fprintf(w, "glide_active_sessions %s\n", formatFloat(ActiveSessions.get()))
+}
+
+func writeCounter(w io.Writer, name, help string, c *counterVec) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", name, labelString(c.labels, key), formatFloat(c.values[key]))
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, h *histogramVec) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeysUint(h.totals) {
+		base := labelPairs(h.labels, key)
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %s\n", name, withLabel(base, "le", formatFloat(le)), strconv.FormatUint(h.counts[key][i], 10))
+		}
+		fmt.Fprintf(w, "%s_bucket%s %s\n", name, withLabel(base, "le", "+Inf"), strconv.FormatUint(h.totals[key], 10))
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, labelString(h.labels, key), formatFloat(h.sums[key]))
+		fmt.Fprintf(w, "%s_count%s %s\n", name, labelString(h.labels, key), strconv.FormatUint(h.totals[key], 10))
+	}
+}
+
+func labelPairs(labelNames []string, key string) []string {
+	values := strings.Split(key, "\x00")
+	pairs := make([]string, len(labelNames))
+	for i, name := range labelNames {
+		pairs[i] = fmt.Sprintf("%s=%q", name, values[i])
+	}
+	return pairs
+}
+
+func withLabel(pairs []string, name, value string) string {
+	all := append(append([]string{}, pairs...), fmt.Sprintf("%s=%q", name, value))
+	return "{" + strings.Join(all, ",") + "}"
+}
+
+func labelString(labelNames []string, key string) string {
+	pairs := labelPairs(labelNames, key)
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysUint(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,52 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ClientRegistry is a static allowlist of which redirect_uris each
+// client_id is permitted to use with Authorize. Without it, Authorize
+// would redirect to whatever redirect_uri a caller supplies as long as
+// it's well-formed, letting anyone mint an authorization code for their
+// own domain by walking a victim through the real phone-auth flow.
+type ClientRegistry struct {
+	clients map[string]map[string]bool
+}
+
+// NewClientRegistry builds a registry from client_id to its allowed
+// redirect_uris.
+func NewClientRegistry(clients map[string][]string) *ClientRegistry {
+	reg := &ClientRegistry{clients: make(map[string]map[string]bool, len(clients))}
+	for clientID, redirectURIs := range clients {
+		allowed := make(map[string]bool, len(redirectURIs))
+		for _, uri := range redirectURIs {
+			allowed[uri] = true
+		}
+		reg.clients[clientID] = allowed
+	}
+	return reg
+}
+
+// Allowed reports whether clientID is registered to use redirectURI.
+func (r *ClientRegistry) Allowed(clientID, redirectURI string) bool {
+	if r == nil || clientID == "" || redirectURI == "" {
+		return false
+	}
+	return r.clients[clientID][redirectURI]
+}
+
+// LoadClientRegistryFromJSON parses the OIDC_CLIENTS_JSON env var: a JSON
+// object mapping client_id to the list of redirect_uris it may present,
+// e.g. {"my-app":["https://app.example.com/callback"]}. An empty raw
+// string yields a registry that allows nothing, matching the fail-closed
+// default for every other authz check in this package.
+func LoadClientRegistryFromJSON(raw string) (*ClientRegistry, error) {
+	clients := make(map[string][]string)
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &clients); err != nil {
+			return nil, fmt.Errorf("parse OIDC_CLIENTS_JSON: %w", err)
+		}
+	}
+	return NewClientRegistry(clients), nil
+}
@@ -0,0 +1,383 @@
+// Package oidc wraps the quickstart's native prepare/process phone-auth
+// flow in an OIDC-compatible authorization-code + PKCE layer, so a
+// third-party app can consume it with a stock OAuth2/OIDC client library
+// instead of talking to the Glide SDK shape directly.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	glide "github.com/GlideIdentity/glide-be-sdk-go"
+)
+
+// pollInterval is how often Authorize checks the upstream status URL
+// while holding the request open for the user to complete the native
+// auth flow.
+const pollInterval = 2 * time.Second
+
+// successStatuses are the terminal states that mean the phone number was
+// verified and an authorization code should be issued.
+var successStatuses = map[string]bool{
+	"AUTH_OK":   true,
+	"COMPLETED": true,
+	"SUCCESS":   true,
+}
+
+// terminalStatuses mirrors webhook.IsTerminalStatus's set; duplicated
+// rather than imported so oidc doesn't take on a dependency on the
+// webhook delivery package for a one-line check.
+var terminalStatuses = map[string]bool{
+	"COMPLETED": true,
+	"SUCCESS":   true,
+	"FAILED":    true,
+	"ERROR":     true,
+	"EXPIRED":   true,
+	"AUTH_OK":   true,
+	"AUTH_FAIL": true,
+}
+
+// AuthorizeRequest is the parsed /oauth2/authorize query string.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	PhoneNumber         string // optional login_hint; falls back to the default T-Mobile PLMN like phoneAuthPrepareHandler does
+}
+
+// authorizationCode is what Authorize hands out and Token redeems exactly
+// once.
+type authorizationCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	phoneNumber   string
+	expiresAt     time.Time
+}
+
+// TokenResponse is what Token returns on a successful code exchange.
+type TokenResponse struct {
+	IDToken   string
+	ExpiresIn int
+}
+
+// DiscoveryDocument is the body served at /.well-known/openid-configuration.
+type DiscoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                  []string `json:"scopes_supported"`
+	GrantTypesSupported              []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+}
+
+// Issuer drives MagicAuth.Prepare/the status poll to hold an
+// /oauth2/authorize request open until the user completes native auth,
+// then mints and redeems short-lived authorization codes for ID tokens.
+type Issuer struct {
+	issuerURL        string
+	glideClient      *glide.Client
+	httpClient       *http.Client
+	apiKey           string
+	authorizeTimeout time.Duration
+	codeTTL          time.Duration
+	idTokenTTL       time.Duration
+	keys             *KeySet
+	clients          *ClientRegistry
+	logger           *slog.Logger
+
+	mu    sync.Mutex
+	codes map[string]*authorizationCode
+}
+
+// NewIssuer builds an Issuer. issuerURL is this server's own externally
+// reachable base URL (used as the "iss" claim and to build the discovery
+// document's endpoint URLs). clients is the allowlist of client_id ->
+// redirect_uris that Authorize will accept.
+func NewIssuer(issuerURL string, glideClient *glide.Client, httpClient *http.Client, apiKey string, keys *KeySet, clients *ClientRegistry, authorizeTimeout, codeTTL, idTokenTTL time.Duration, logger *slog.Logger) *Issuer {
+	iss := &Issuer{
+		issuerURL:        issuerURL,
+		glideClient:      glideClient,
+		httpClient:       httpClient,
+		apiKey:           apiKey,
+		authorizeTimeout: authorizeTimeout,
+		codeTTL:          codeTTL,
+		idTokenTTL:       idTokenTTL,
+		keys:             keys,
+		clients:          clients,
+		logger:           logger,
+		codes:            make(map[string]*authorizationCode),
+	}
+	go iss.codeCleanupLoop()
+	return iss
+}
+
+// codeCleanupLoop prunes expired, never-redeemed authorization codes once a
+// minute, mirroring memorySessionStore.cleanupLoop. Without it, an
+// /oauth2/authorize flow abandoned before the user completes native auth -
+// or before Token is ever called - would hold its entry in codes forever,
+// since Token only deletes the entry it successfully redeems.
+func (iss *Issuer) codeCleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	for range ticker.C {
+		iss.mu.Lock()
+		now := time.Now()
+		for code, entry := range iss.codes {
+			if entry.expiresAt.Before(now) {
+				delete(iss.codes, code)
+			}
+		}
+		iss.mu.Unlock()
+	}
+}
+
+// StartKeyRotation rotates the signing key every interval until ctx is
+// canceled, giving RPs caching /jwks.json a predictable overlap window
+// instead of a signing key that lives forever.
+func (iss *Issuer) StartKeyRotation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := iss.keys.Rotate(); err != nil {
+					iss.logger.Error("failed to rotate oidc signing key", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Authorize drives MagicAuth.Prepare for req, holds the request open
+// polling the resulting status URL until the user completes native auth
+// or authorizeTimeout elapses, and returns the redirect_uri the caller
+// should send the browser to - either "?code=...&state=..." on success or
+// "?error=...&state=..." per RFC 6749 section 4.1.2.1 otherwise. Only a request
+// malformed badly enough that redirecting would itself be unsafe - no
+// parseable redirect_uri, or a redirect_uri not registered to client_id -
+// returns a non-nil error instead.
+func (iss *Issuer) Authorize(ctx context.Context, req AuthorizeRequest) (string, error) {
+	redirectURL, err := url.Parse(req.RedirectURI)
+	if err != nil || redirectURL.Scheme == "" || redirectURL.Host == "" {
+		return "", errors.New("redirect_uri must be an absolute http(s) URL")
+	}
+	if !iss.clients.Allowed(req.ClientID, req.RedirectURI) {
+		return "", errors.New("redirect_uri is not registered for client_id")
+	}
+
+	if req.ResponseType != "code" {
+		return iss.errorRedirect(redirectURL, req.State, "unsupported_response_type"), nil
+	}
+	if req.ClientID == "" {
+		return iss.errorRedirect(redirectURL, req.State, "invalid_request"), nil
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return iss.errorRedirect(redirectURL, req.State, "invalid_request"), nil
+	}
+
+	prepareReq := &glide.PrepareRequest{UseCase: glide.UseCaseGetPhoneNumber}
+	if req.PhoneNumber != "" {
+		prepareReq.PhoneNumber = req.PhoneNumber
+	} else {
+		prepareReq.PLMN = &glide.PLMN{MCC: "310", MNC: "260"} // default T-Mobile USA, matching phoneAuthPrepareHandler
+	}
+
+	resp, err := iss.glideClient.MagicAuth.Prepare(ctx, prepareReq)
+	if err != nil {
+		iss.logger.Warn("oidc authorize: prepare failed", "error", err)
+		return iss.errorRedirect(redirectURL, req.State, "server_error"), nil
+	}
+
+	statusURL := glide.GetStatusURL(resp)
+	if statusURL == "" {
+		return iss.errorRedirect(redirectURL, req.State, "server_error"), nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, iss.authorizeTimeout)
+	defer cancel()
+
+	status, result, err := iss.waitForTerminal(waitCtx, statusURL)
+	if err != nil {
+		iss.logger.Warn("oidc authorize: status wait failed", "error", err)
+		return iss.errorRedirect(redirectURL, req.State, "server_error"), nil
+	}
+
+	phoneNumber, _ := result["phone_number"].(string)
+	if phoneNumber == "" {
+		phoneNumber, _ = result["phoneNumber"].(string)
+	}
+	if !successStatuses[status] || phoneNumber == "" {
+		return iss.errorRedirect(redirectURL, req.State, "access_denied"), nil
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return iss.errorRedirect(redirectURL, req.State, "server_error"), nil
+	}
+
+	iss.mu.Lock()
+	iss.codes[code] = &authorizationCode{
+		clientID:      req.ClientID,
+		redirectURI:   req.RedirectURI,
+		codeChallenge: req.CodeChallenge,
+		phoneNumber:   phoneNumber,
+		expiresAt:     time.Now().Add(iss.codeTTL),
+	}
+	iss.mu.Unlock()
+
+	q := redirectURL.Query()
+	q.Set("code", code)
+	if req.State != "" {
+		q.Set("state", req.State)
+	}
+	redirectURL.RawQuery = q.Encode()
+	return redirectURL.String(), nil
+}
+
+func (iss *Issuer) errorRedirect(redirectURL *url.URL, state, errorCode string) string {
+	out := *redirectURL
+	q := out.Query()
+	q.Set("error", errorCode)
+	if state != "" {
+		q.Set("state", state)
+	}
+	out.RawQuery = q.Encode()
+	return out.String()
+}
+
+// Token redeems a single-use authorization code for a signed ID token.
+// The error returned, when non-nil, is the OAuth2 error code
+// ("invalid_grant" or "invalid_client") the caller should report per
+// RFC 6749 section 5.2.
+func (iss *Issuer) Token(clientID, code, verifier, redirectURI string) (TokenResponse, error) {
+	iss.mu.Lock()
+	entry, ok := iss.codes[code]
+	if ok {
+		delete(iss.codes, code) // single use, whether or not the rest of the exchange succeeds
+	}
+	iss.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return TokenResponse{}, errors.New("invalid_grant")
+	}
+	if entry.clientID != clientID || entry.redirectURI != redirectURI {
+		return TokenResponse{}, errors.New("invalid_client")
+	}
+	if !VerifyPKCE(verifier, entry.codeChallenge) {
+		return TokenResponse{}, errors.New("invalid_grant")
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Issuer:      iss.issuerURL,
+		Subject:     entry.phoneNumber,
+		Audience:    entry.clientID,
+		IssuedAt:    now.Unix(),
+		ExpiresAt:   now.Add(iss.idTokenTTL).Unix(),
+		PhoneNumber: entry.phoneNumber,
+	}
+
+	key, kid := iss.keys.Current()
+	idToken, err := SignIDToken(claims, key, kid)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("invalid_grant: %w", err)
+	}
+
+	return TokenResponse{IDToken: idToken, ExpiresIn: int(iss.idTokenTTL.Seconds())}, nil
+}
+
+// Discovery returns the /.well-known/openid-configuration body.
+func (iss *Issuer) Discovery() DiscoveryDocument {
+	return DiscoveryDocument{
+		Issuer:                           iss.issuerURL,
+		AuthorizationEndpoint:            iss.issuerURL + "/oauth2/authorize",
+		TokenEndpoint:                    iss.issuerURL + "/oauth2/token",
+		JWKSURI:                          iss.issuerURL + "/jwks.json",
+		ResponseTypesSupported:           []string{"code"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{"RS256"},
+		ScopesSupported:                  []string{"phone"},
+		GrantTypesSupported:              []string{"authorization_code"},
+		CodeChallengeMethodsSupported:    []string{"S256"},
+		ClaimsSupported:                  []string{"sub", "iss", "aud", "exp", "iat", "phone_number"},
+	}
+}
+
+// JWKS returns the /jwks.json body.
+func (iss *Issuer) JWKS() JWKSDocument {
+	return iss.keys.JWKS()
+}
+
+// waitForTerminal polls statusURL until it reports a terminal status or
+// ctx is done.
+func (iss *Issuer) waitForTerminal(ctx context.Context, statusURL string) (string, map[string]interface{}, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, result, terminal, err := iss.fetchStatus(ctx, statusURL)
+		if err == nil && terminal {
+			return status, result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (iss *Issuer) fetchStatus(ctx context.Context, statusURL string) (status string, result map[string]interface{}, terminal bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return "", nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if iss.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+iss.apiKey)
+	}
+
+	resp, err := iss.httpClient.Do(req)
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", nil, false, err
+	}
+
+	statusValue, _ := body["status"].(string)
+	return statusValue, body, terminalStatuses[statusValue], nil
+}
+
+func newCode() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sync"
+)
+
+// signingKey is one RSA keypair published under a kid so relying parties
+// can tell which public key validates a given token.
+type signingKey struct {
+	kid     string
+	private *rsa.PrivateKey
+}
+
+// KeySet holds the active RS256 signing key plus the one it replaced, so
+// a rotation doesn't invalidate ID tokens that are still in flight: the
+// previous key stays published in JWKS until the next rotation retires it.
+type KeySet struct {
+	mu       sync.RWMutex
+	current  *signingKey
+	previous *signingKey
+}
+
+// NewKeySet generates the first signing key for a fresh Issuer.
+func NewKeySet() (*KeySet, error) {
+	key, err := newSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return &KeySet{current: key}, nil
+}
+
+func newSigningKey() (*signingKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := randomKID()
+	if err != nil {
+		return nil, err
+	}
+	return &signingKey{kid: kid, private: private}, nil
+}
+
+func randomKID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Current returns the key new tokens should be signed with.
+func (ks *KeySet) Current() (*rsa.PrivateKey, string) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.current.private, ks.current.kid
+}
+
+// Rotate generates a fresh signing key and demotes the outgoing one to
+// "previous", where it remains valid for verification (via JWKS) for one
+// more rotation interval before falling out of the set entirely.
+func (ks *KeySet) Rotate() error {
+	next, err := newSigningKey()
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	ks.previous = ks.current
+	ks.current = next
+	ks.mu.Unlock()
+	return nil
+}
+
+// JWK is a single RSA public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSDocument is the body served at /jwks.json.
+type JWKSDocument struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS renders the current (and, during a rotation's grace window,
+// previous) signing key as a JWK set for relying parties to verify
+// against.
+func (ks *KeySet) JWKS() JWKSDocument {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := []JWK{toJWK(ks.current)}
+	if ks.previous != nil {
+		keys = append(keys, toJWK(ks.previous))
+	}
+	return JWKSDocument{Keys: keys}
+}
+
+func toJWK(k *signingKey) JWK {
+	pub := k.private.PublicKey
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.kid,
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker trips when too many upstream Glide errors land within a
+// rolling window, so a struggling upstream doesn't get hammered by retries
+// while it's already failing.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	errors    []time.Time
+	trippedAt time.Time
+	isTripped bool
+}
+
+// NewCircuitBreaker trips after threshold errors within window, and stays
+// tripped for cooldown before allowing traffic through again.
+func NewCircuitBreaker(threshold int, window, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, window: window, cooldown: cooldown}
+}
+
+// Allow reports whether a request should be let through. When tripped, it
+// also returns the remaining cooldown so the caller can set Retry-After.
+func (c *CircuitBreaker) Allow() (bool, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isTripped {
+		return true, 0
+	}
+
+	remaining := c.cooldown - time.Since(c.trippedAt)
+	if remaining <= 0 {
+		c.isTripped = false
+		c.errors = nil
+		return true, 0
+	}
+	return false, remaining
+}
+
+// RecordError notes an upstream failure and trips the breaker once
+// threshold failures land within window.
+func (c *CircuitBreaker) RecordError() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.errors = append(c.errors, now)
+
+	cutoff := now.Add(-c.window)
+	kept := c.errors[:0]
+	for _, t := range c.errors {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	c.errors = kept
+
+	if len(c.errors) >= c.threshold {
+		c.isTripped = true
+		c.trippedAt = now
+	}
+}
+
+// RecordSuccess clears the error history so an isolated blip doesn't keep
+// counting toward the trip threshold indefinitely.
+func (c *CircuitBreaker) RecordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors = nil
+}
@@ -0,0 +1,179 @@
+// Package ratelimit implements the abuse-protection layer in front of the
+// phone-auth endpoints: a per-IP token bucket, a per-phone-number fixed
+// window counter (shared across instances only with the Redis counter
+// store), and a circuit breaker for the upstream Glide API.
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"magical-auth-quickstart-go/apiutil"
+)
+
+// IPLimiter hands out one token-bucket limiter per client IP, so a single
+// abusive caller can't exhaust the rate limit for everyone else.
+type IPLimiter struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	rps      rate.Limit
+	burst    int
+}
+
+// NewIPLimiter builds a limiter allowing rps requests per second per IP,
+// with bursts up to burst.
+func NewIPLimiter(rps float64, burst int) *IPLimiter {
+	return &IPLimiter{
+		limiters: make(map[string]*rate.Limiter),
+		rps:      rate.Limit(rps),
+		burst:    burst,
+	}
+}
+
+func (l *IPLimiter) limiterFor(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, ok := l.limiters[ip]
+	if !ok {
+		limiter = rate.NewLimiter(l.rps, l.burst)
+		l.limiters[ip] = limiter
+	}
+	return limiter
+}
+
+// Middleware rejects requests over the per-IP rate with a 429 problem+json
+// response, setting X-RateLimit-Remaining/X-RateLimit-Reset and, when
+// throttled, Retry-After so the frontend can render backoff UI instead of
+// just failing silently.
+func (l *IPLimiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := l.limiterFor(ClientID(r))
+
+		reservation := limiter.Reserve()
+		if !reservation.OK() {
+			apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeUnexpectedError, "rate limit misconfigured"))
+			return
+		}
+
+		delay := reservation.Delay()
+		w.Header().Set("X-RateLimit-Reset", resetSeconds(delay))
+		if delay > 0 {
+			reservation.Cancel()
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", RetryAfterSeconds(delay))
+			apiutil.WriteError(w, r, apiutil.New(http.StatusTooManyRequests, apiutil.CodeRateLimited, "Too many requests"))
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Remaining", formatTokens(limiter.Tokens()))
+		next(w, r)
+	}
+}
+
+// ClientID identifies the caller for rate limiting and idempotency: the
+// client's own API key when it sends one (X-API-Key), since that's a
+// stabler identity than an IP behind NAT or a mobile carrier gateway,
+// falling back to the client IP otherwise.
+func ClientID(r *http.Request) string {
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		return "key:" + apiKey
+	}
+	return "ip:" + clientIP(r)
+}
+
+var (
+	trustedProxiesMu sync.RWMutex
+	trustedProxies   []*net.IPNet
+)
+
+// SetTrustedProxies configures the CIDR ranges (typically a load balancer
+// or reverse proxy subnet) whose X-Forwarded-For/X-Real-IP headers
+// clientIP will honor. Without a configured, matching proxy, clientIP
+// always falls back to r.RemoteAddr: trusting those headers from an
+// arbitrary caller would let them mint a fresh per-IP rate-limit bucket
+// on every request just by changing a header.
+func SetTrustedProxies(cidrs []string) error {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("parse trusted proxy CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, ipNet)
+	}
+
+	trustedProxiesMu.Lock()
+	trustedProxies = nets
+	trustedProxiesMu.Unlock()
+	return nil
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	trustedProxiesMu.RLock()
+	defer trustedProxiesMu.RUnlock()
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if isTrustedProxy(net.ParseIP(host)) {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			if first := strings.TrimSpace(strings.SplitN(fwd, ",", 2)[0]); first != "" {
+				return first
+			}
+		}
+		if real := r.Header.Get("X-Real-IP"); real != "" {
+			return real
+		}
+	}
+
+	return host
+}
+
+// RetryAfterSeconds rounds up so Retry-After never tells a client to retry
+// before the delay has actually elapsed.
+func RetryAfterSeconds(d time.Duration) string {
+	seconds := int(d.Seconds()) + 1
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// resetSeconds reports how long until the limiter state reported alongside
+// it (typically 0 when the request was allowed) is no longer current.
+func resetSeconds(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds < 0 {
+		seconds = 0
+	}
+	return strconv.Itoa(seconds)
+}
+
+func formatTokens(tokens float64) string {
+	if tokens < 0 {
+		tokens = 0
+	}
+	return strconv.Itoa(int(tokens))
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PhoneLimiter throttles prepares for the same E.164 number using a fixed
+// window counter keyed by phone number. The count is shared across
+// instances only when the CounterStore is Redis-backed; the in-memory store
+// (also used for SESSION_STORE=sql and postgres) counts per instance.
+type PhoneLimiter struct {
+	store  CounterStore
+	limit  int
+	window time.Duration
+}
+
+// NewPhoneLimiter builds a PhoneLimiter backed by the given CounterStore,
+// allowing up to limit prepares per phone number within window.
+func NewPhoneLimiter(store CounterStore, limit int, window time.Duration) *PhoneLimiter {
+	return &PhoneLimiter{store: store, limit: limit, window: window}
+}
+
+// Allow increments the counter for phoneNumber and reports whether this
+// request is within the configured limit.
+func (p *PhoneLimiter) Allow(ctx context.Context, phoneNumber string) (bool, error) {
+	if phoneNumber == "" || p.limit <= 0 {
+		return true, nil
+	}
+	count, err := p.store.Increment(ctx, "phone:"+phoneNumber, p.window)
+	if err != nil {
+		return false, err
+	}
+	return count <= p.limit, nil
+}
+
+// CounterStore increments a fixed-window counter: the window starts at the
+// key's first increment and the count resets once it elapses, so it is not
+// a sliding window. It mirrors SessionStore's pluggable-backend design
+// (selected by the same SESSION_STORE env var) but tracks counts instead
+// of session metadata, since the two have different storage shapes.
+type CounterStore interface {
+	Increment(ctx context.Context, key string, window time.Duration) (int, error)
+}
+
+// NewCounterStore builds the CounterStore selected by backend ("memory",
+// "redis", or "sql"), mirroring NewSessionStore's backend selection.
+func NewCounterStore(backend string) (CounterStore, error) {
+	switch backend {
+	case "", "memory":
+		return newMemoryCounterStore(), nil
+	case "redis":
+		return newRedisCounterStoreFromEnv()
+	case "sql", "postgres":
+		return newMemoryCounterStore(), nil // counters are best-effort; SQL backend would add write load disproportionate to its value here
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE backend %q", backend)
+	}
+}
+
+type memoryCounterStore struct {
+	mu      sync.Mutex
+	entries map[string]*counterEntry
+}
+
+type counterEntry struct {
+	count     int
+	expiresAt time.Time
+}
+
+func newMemoryCounterStore() *memoryCounterStore {
+	return &memoryCounterStore{entries: make(map[string]*counterEntry)}
+}
+
+func (s *memoryCounterStore) Increment(_ context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := s.entries[key]
+	if !ok || entry.expiresAt.Before(now) {
+		entry = &counterEntry{count: 0, expiresAt: now.Add(window)}
+		s.entries[key] = entry
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+type redisCounterStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisCounterStoreFromEnv() (*redisCounterStore, error) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return nil, errors.New("REDIS_ADDR must be set when SESSION_STORE=redis")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	return &redisCounterStore{client: client, prefix: "glide:ratelimit:"}, nil
+}
+
+func (s *redisCounterStore) Increment(ctx context.Context, key string, window time.Duration) (int, error) {
+	fullKey := s.prefix + key
+	count, err := s.client.Incr(ctx, fullKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Set the expiry whenever the key doesn't have one yet, rather than only
+	// on the first increment, so a transient Expire failure self-heals on
+	// the next request instead of leaving the counter stuck forever.
+	if ttl, err := s.client.TTL(ctx, fullKey).Result(); err == nil && ttl < 0 {
+		s.client.Expire(ctx, fullKey, window)
+	}
+	return int(count), nil
+}
@@ -3,11 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"magical-auth-quickstart-go/apiutil"
+	"magical-auth-quickstart-go/idempotency"
+	"magical-auth-quickstart-go/logging"
+	"magical-auth-quickstart-go/metrics"
+	"magical-auth-quickstart-go/oidc"
+	"magical-auth-quickstart-go/ratelimit"
+	"magical-auth-quickstart-go/streaming"
+	"magical-auth-quickstart-go/tracing"
+	"magical-auth-quickstart-go/webhook"
 
 	glide "github.com/GlideIdentity/glide-be-sdk-go"
 	"github.com/joho/godotenv"
@@ -24,20 +38,82 @@ type HealthCheckResponse struct {
 	} `json:"env"`
 }
 
-type ErrorResponse struct {
-	Error     string                 `json:"error"`
-	Message   string                 `json:"message"`
-	RequestID string                 `json:"requestId,omitempty"`
-	Details   map[string]interface{} `json:"details,omitempty"`
+// defaultSessionTTL is how long a prepare response's status URL stays
+// valid for the polling proxy when SESSION_TTL is unset.
+const defaultSessionTTL = 5 * time.Minute
+
+// defaultIdempotencyTTL is how long a phoneAuthProcessHandler response stays
+// replayable under its Idempotency-Key when IDEMPOTENCY_TTL is unset.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyReservationTTL bounds how long a concurrent duplicate request
+// is rejected while the first request for an Idempotency-Key is still in
+// flight. It's far shorter than defaultIdempotencyTTL so a crashed or
+// abandoned first attempt doesn't wedge retries for the full cache window.
+const idempotencyReservationTTL = 30 * time.Second
+
+// OIDC defaults: how long /oauth2/authorize holds a request open waiting
+// for native auth to complete, how long the authorization code it mints
+// stays redeemable, how long the resulting ID token is valid for, and how
+// often the RS256 signing key rotates.
+const (
+	defaultOIDCAuthorizeTimeout = 2 * time.Minute
+	defaultOIDCCodeTTL          = time.Minute
+	defaultOIDCIDTokenTTL       = time.Hour
+	defaultOIDCKeyRotation      = 24 * time.Hour
+)
+
+// sessionTTLFromEnv parses SESSION_TTL (a Go duration string, e.g. "10m")
+// and falls back to defaultSessionTTL when it's unset or invalid.
+func sessionTTLFromEnv() time.Duration {
+	return envDuration("SESSION_TTL", defaultSessionTTL)
+}
+
+// idempotencyTTLFromEnv parses IDEMPOTENCY_TTL and falls back to
+// defaultIdempotencyTTL when it's unset or invalid.
+func idempotencyTTLFromEnv() time.Duration {
+	return envDuration("IDEMPOTENCY_TTL", defaultIdempotencyTTL)
 }
 
-var glideClient *glide.Client
+// envDuration parses key as a Go duration string (e.g. "10m"), falling
+// back when it's unset, malformed, or non-positive.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed <= 0 {
+		return fallback
+	}
+	return parsed
+}
+
+// App holds the dependencies the HTTP handlers need. Handlers are methods
+// on App rather than package-level functions so the session store backend
+// can be swapped without touching the routing or handler logic.
+type App struct {
+	glideClient    *glide.Client
+	sessionStore   SessionStore
+	sessionTTL     time.Duration
+	idempotency    idempotency.Cache
+	idempotencyTTL time.Duration
+	apiBaseURL     string
+	httpClient     *http.Client
+	phoneLimiter   *ratelimit.PhoneLimiter
+	breaker        *ratelimit.CircuitBreaker
+	webhooks       *webhook.Dispatcher
+	streams        *streaming.Hub
+	oidcIssuer     *oidc.Issuer
+}
 
 func main() {
+	logger := logging.New()
+
 	// Load environment variables
 	err := godotenv.Load()
 	if err != nil {
-		log.Println("No .env file found, using environment variables")
+		logger.Info("no .env file found, using environment variables")
 	}
 
 	port := os.Getenv("PORT")
@@ -51,22 +127,16 @@ func main() {
 	logLevel := os.Getenv("GLIDE_LOG_LEVEL")
 
 	if debugMode || logLevel == "debug" {
-		log.Println("🔍 Debug logging enabled for Glide SDK")
-		log.Println("📊 Configuration:")
-		log.Printf("  - GLIDE_DEBUG: %s", os.Getenv("GLIDE_DEBUG"))
-		log.Printf("  - GLIDE_LOG_LEVEL: %s", logLevel)
-		log.Println("📡 You will see detailed logs for:")
-		log.Println("  - API request/response details")
-		log.Println("  - Performance metrics")
-		log.Println("  - Retry attempts")
-		log.Println("  - Error context")
-		log.Println("🔒 Sensitive data is automatically sanitized")
+		logger.Debug("debug logging enabled for Glide SDK",
+			"glide_debug", os.Getenv("GLIDE_DEBUG"),
+			"glide_log_level", logLevel,
+		)
 	}
 
 	// Initialize Glide client
 	apiKey := os.Getenv("GLIDE_API_KEY")
 	if apiKey == "" {
-		log.Println("⚠️  Missing Glide API key. Please check your .env file.")
+		logger.Warn("missing Glide API key, check your .env file")
 	}
 
 	apiBaseURL := os.Getenv("GLIDE_API_BASE_URL")
@@ -99,22 +169,128 @@ func main() {
 			opts = append(opts, glide.WithLogFormat(glide.LogFormatPretty))
 		}
 		if debugMode || logLevel == "debug" {
-			log.Printf("  - GLIDE_LOG_FORMAT: %s", logFormat)
+			logger.Debug("glide log format", "format", logFormat)
 		}
 	}
 
-	glideClient = glide.New(opts...)
+	sessionBackend := os.Getenv("SESSION_STORE")
+	sessionStore, err := NewSessionStore(sessionBackend)
+	if err != nil {
+		logger.Error("failed to initialize session store", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("session store initialized", "backend", sessionBackendLabel(sessionBackend))
+
+	counterStore, err := ratelimit.NewCounterStore(sessionBackend)
+	if err != nil {
+		logger.Error("failed to initialize rate limit counter store", "error", err)
+		os.Exit(1)
+	}
+
+	subscriberStore, err := webhook.NewSubscriberStore(sessionBackend)
+	if err != nil {
+		logger.Error("failed to initialize webhook subscriber store", "error", err)
+		os.Exit(1)
+	}
+
+	idempotencyCache, err := idempotency.NewCache(sessionBackend)
+	if err != nil {
+		logger.Error("failed to initialize idempotency cache", "error", err)
+		os.Exit(1)
+	}
+
+	oidcKeys, err := oidc.NewKeySet()
+	if err != nil {
+		logger.Error("failed to generate oidc signing key", "error", err)
+		os.Exit(1)
+	}
+
+	issuerURL := os.Getenv("OIDC_ISSUER_URL")
+	if issuerURL == "" {
+		issuerURL = "http://localhost:" + port
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	glideClient := glide.New(opts...)
+
+	oidcClients, err := oidc.LoadClientRegistryFromJSON(os.Getenv("OIDC_CLIENTS_JSON"))
+	if err != nil {
+		logger.Error("failed to parse OIDC_CLIENTS_JSON", "error", err)
+		os.Exit(1)
+	}
+
+	oidcIssuer := oidc.NewIssuer(issuerURL, glideClient, httpClient, apiKey, oidcKeys, oidcClients,
+		envDuration("OIDC_AUTHORIZE_TIMEOUT", defaultOIDCAuthorizeTimeout),
+		envDuration("OIDC_CODE_TTL", defaultOIDCCodeTTL),
+		envDuration("OIDC_ID_TOKEN_TTL", defaultOIDCIDTokenTTL),
+		logger)
+	oidcIssuer.StartKeyRotation(context.Background(), envDuration("OIDC_KEY_ROTATION_INTERVAL", defaultOIDCKeyRotation))
+
+	app := &App{
+		glideClient:    glideClient,
+		sessionStore:   sessionStore,
+		sessionTTL:     sessionTTLFromEnv(),
+		idempotency:    idempotencyCache,
+		idempotencyTTL: idempotencyTTLFromEnv(),
+		apiBaseURL:     apiBaseURL,
+		httpClient:     httpClient,
+		phoneLimiter:   ratelimit.NewPhoneLimiter(counterStore, envInt("RATE_LIMIT_PHONE_PER_HOUR", 5), time.Hour),
+		breaker:        ratelimit.NewCircuitBreaker(envInt("CIRCUIT_BREAKER_THRESHOLD", 10), time.Minute, 30*time.Second),
+		webhooks:       webhook.NewDispatcher(subscriberStore, httpClient, os.Getenv("GLIDE_API_KEY"), logger),
+		streams:        streaming.NewHub(httpClient, os.Getenv("GLIDE_API_KEY"), logger),
+		oidcIssuer:     oidcIssuer,
+	}
+
+	if raw := os.Getenv("TRUSTED_PROXIES"); raw != "" {
+		cidrs := strings.Split(raw, ",")
+		for i, cidr := range cidrs {
+			cidrs[i] = strings.TrimSpace(cidr)
+		}
+		if err := ratelimit.SetTrustedProxies(cidrs); err != nil {
+			logger.Error("invalid TRUSTED_PROXIES", "error", err)
+			os.Exit(1)
+		}
+	}
 
 	// Setup routes
 	mux := http.NewServeMux()
 
+	prepareLimiter := ratelimit.NewIPLimiter(envFloat("RATE_LIMIT_PREPARE_RPS", 1), envInt("RATE_LIMIT_PREPARE_BURST", 5))
+	processLimiter := ratelimit.NewIPLimiter(envFloat("RATE_LIMIT_PROCESS_RPS", 2), envInt("RATE_LIMIT_PROCESS_BURST", 10))
+	statusLimiter := ratelimit.NewIPLimiter(envFloat("RATE_LIMIT_STATUS_RPS", 5), envInt("RATE_LIMIT_STATUS_BURST", 20))
+	streamLimiter := ratelimit.NewIPLimiter(envFloat("RATE_LIMIT_STREAM_RPS", 2), envInt("RATE_LIMIT_STREAM_BURST", 5))
+	webhooksLimiter := ratelimit.NewIPLimiter(envFloat("RATE_LIMIT_WEBHOOKS_RPS", 1), envInt("RATE_LIMIT_WEBHOOKS_BURST", 5))
+
+	go activeSessionsLoop(app.sessionStore)
+
 	// Health check endpoint
-	mux.HandleFunc("/api/health", healthCheckHandler)
+	mux.HandleFunc("/api/health", app.healthCheckHandler)
+
+	// Observability
+	mux.HandleFunc("/metrics", metricsHandler)
+	if os.Getenv("ENABLE_PPROF") == "true" {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		logger.Info("pprof mounted at /debug/pprof")
+	}
 
 	// Phone Auth endpoints
-	mux.HandleFunc("/api/phone-auth/prepare", phoneAuthPrepareHandler)
-	mux.HandleFunc("/api/phone-auth/process", phoneAuthProcessHandler)
-	mux.HandleFunc("/api/phone-auth/status/", phoneAuthStatusHandler)
+	mux.HandleFunc("/api/phone-auth/prepare", prepareLimiter.Middleware(app.phoneAuthPrepareHandler))
+	mux.HandleFunc("/api/phone-auth/process", processLimiter.Middleware(app.phoneAuthProcessHandler))
+	mux.HandleFunc("/api/phone-auth/status/", statusLimiter.Middleware(app.phoneAuthStatusHandler))
+	mux.HandleFunc("/api/phone-auth/stream/", streamLimiter.Middleware(app.phoneAuthStreamHandler))
+
+	// Webhook subscriptions
+	mux.HandleFunc("/api/webhooks", webhooksLimiter.Middleware(app.registerWebhookHandler))
+
+	// OIDC wrapper around the native phone-auth flow
+	mux.HandleFunc("/oauth2/authorize", prepareLimiter.Middleware(app.oauth2AuthorizeHandler))
+	mux.HandleFunc("/oauth2/token", app.oauth2TokenHandler)
+	mux.HandleFunc("/.well-known/openid-configuration", app.openIDConfigurationHandler)
+	mux.HandleFunc("/jwks.json", app.jwksHandler)
 
 	// Setup CORS
 	c := cors.New(cors.Options{
@@ -124,103 +300,189 @@ func main() {
 		Debug:          false,
 	})
 
-	handler := c.Handler(mux)
+	handler := apiutil.Recover(logging.Middleware(logger)(c.Handler(mux)))
 
-	log.Printf("Server running on http://localhost:%s\n", port)
-	log.Printf("Using Glide API: %s\n", apiBaseURL)
+	logger.Info("server starting", "port", port, "glide_api_base_url", apiBaseURL)
 
 	if err := http.ListenAndServe(":"+port, handler); err != nil {
-		log.Fatal(err)
+		logger.Error("server exited", "error", err)
+		os.Exit(1)
 	}
 }
 
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+// activeSessionsLoop keeps the glide_active_sessions gauge roughly current
+// by polling the session store's own count rather than threading a
+// counter through every Put/Delete call site.
+func activeSessionsLoop(store SessionStore) {
+	ticker := time.NewTicker(15 * time.Second)
+	for range ticker.C {
+		if count, err := store.Count(context.Background()); err == nil {
+			metrics.ActiveSessions.Set(float64(count))
+		}
+	}
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.WriteTo(w)
+}
+
+func sessionBackendLabel(backend string) string {
+	if backend == "" {
+		return "memory"
+	}
+	return backend
+}
+
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// writeRetryAfter sets the Retry-After header, reusing the same
+// round-up-by-one rule the per-IP limiter middleware applies.
+func writeRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", ratelimit.RetryAfterSeconds(d))
+}
+
+func (a *App) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiutil.MethodNotAllowed(w, r)
 		return
 	}
 
 	response := HealthCheckResponse{
 		Status:           "ok",
-		GlideInitialized: glideClient != nil,
+		GlideInitialized: a.glideClient != nil,
 		GlideProperties:  []string{"magicAuth", "simSwap", "numberVerify", "kyc"},
 	}
 
 	response.Env.HasAPIKey = os.Getenv("GLIDE_API_KEY") != ""
-	response.Env.APIBaseURL = os.Getenv("GLIDE_API_BASE_URL")
-	if response.Env.APIBaseURL == "" {
-		response.Env.APIBaseURL = "https://api.glideidentity.app"
-	}
+	response.Env.APIBaseURL = a.apiBaseURL
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func phoneAuthPrepareHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) phoneAuthPrepareHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apiutil.MethodNotAllowed(w, r)
 		return
 	}
 
 	var req glide.PrepareRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", nil)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeInvalidRequest, "Invalid request body"))
 		return
 	}
 
-	// Only log if not using pretty format to avoid duplicate logs
-	if os.Getenv("GLIDE_LOG_FORMAT") != "pretty" {
-		log.Printf("/api/phone-auth/prepare %+v\n", req)
+	logger := logging.WithUseCase(logging.FromContext(r.Context()), string(req.UseCase))
+	logger.Debug("prepare request received", "phone_number", req.PhoneNumber)
+
+	ctx, span := tracing.Start(r.Context(), "prepare")
+	defer span.End(logger)
+
+	result := "error"
+	strategy := ""
+	defer func() { metrics.PrepareTotal.Inc(string(req.UseCase), strategy, result) }()
+
+	if allowed, retryAfter := a.breaker.Allow(); !allowed {
+		logger.Warn("circuit breaker open, short-circuiting prepare")
+		writeRetryAfter(w, retryAfter)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusServiceUnavailable, apiutil.CodeServiceUnavailable, "Upstream is currently unavailable, please retry shortly"))
+		return
+	}
+
+	if allowed, err := a.phoneLimiter.Allow(ctx, req.PhoneNumber); err != nil {
+		logger.Warn("failed to check phone rate limit", "error", err)
+	} else if !allowed {
+		logger.Info("phone number rate limited", "phone_number", req.PhoneNumber)
+		writeRetryAfter(w, time.Hour)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusTooManyRequests, apiutil.CodeRateLimited, "Too many prepare requests for this phone number"))
+		return
 	}
 
 	// Set default T-Mobile PLMN for GetPhoneNumber if neither phone_number nor PLMN provided
 	if req.UseCase == glide.UseCaseGetPhoneNumber && req.PhoneNumber == "" && (req.PLMN == nil || req.PLMN.MCC == "" || req.PLMN.MNC == "") {
-		log.Println("No phone_number or PLMN provided for GetPhoneNumber, using default T-Mobile PLMN")
+		logger.Debug("no phone_number or PLMN provided for GetPhoneNumber, using default T-Mobile PLMN")
 		req.PLMN = &glide.PLMN{
 			MCC: "310",
 			MNC: "260", // T-Mobile USA
 		}
 	}
 
-	if os.Getenv("GLIDE_LOG_FORMAT") != "pretty" {
-		log.Printf("Calling glide.MagicAuth.Prepare with: %+v\n", req)
-	}
-
 	// Call Glide SDK
-	ctx := context.Background()
-	response, err := glideClient.MagicAuth.Prepare(ctx, &req)
+	response, err := a.glideClient.MagicAuth.Prepare(ctx, &req)
 	if err != nil {
-		handleGlideError(w, err)
+		a.breaker.RecordError()
+		apiutil.WriteError(w, r, problemFromGlideError(r, err))
 		return
 	}
-
-	if os.Getenv("GLIDE_LOG_FORMAT") != "pretty" {
-		log.Printf("Response from SDK: %+v\n", response)
-	}
+	a.breaker.RecordSuccess()
 
 	// The Go SDK returns the response in the correct format
 	if response.AuthenticationStrategy != "" && response.Data != nil && response.Session.SessionKey != "" {
-		if os.Getenv("GLIDE_LOG_FORMAT") != "pretty" {
-			log.Printf("Forwarding response from SDK: %+v\n", response)
+		logger = logging.WithSession(logger, response.Session.SessionKey)
+		logger.Info("prepare succeeded", "strategy", response.AuthenticationStrategy)
+		result, strategy = "success", response.AuthenticationStrategy
+		span.SetAttr("strategy", strategy)
+
+		if statusURL := glide.GetStatusURL(response); statusURL != "" {
+			entry := sessionEntry{
+				StatusURL: statusURL,
+				UseCase:   string(req.UseCase),
+				PLMN:      req.PLMN,
+				CreatedAt: time.Now(),
+			}
+			if err := a.sessionStore.Put(ctx, response.Session.SessionKey, entry, a.sessionTTL); err != nil {
+				logger.Warn("failed to store session status URL", "error", err)
+			}
+			a.webhooks.StartPolling(response.Session.SessionKey, string(req.UseCase), statusURL, ratelimit.ClientID(r), a.sessionTTL)
 		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 	} else {
-		sendErrorResponse(w, http.StatusInternalServerError, "UNEXPECTED_RESPONSE", "Unexpected response format from Glide SDK", nil)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeUnexpectedResponse, "Unexpected response format from Glide SDK"))
 	}
 }
 
-func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apiutil.MethodNotAllowed(w, r)
 		return
 	}
 
+	logger := logging.FromContext(r.Context())
+
 	// Decode the request body into a generic map to pass through
 	var reqBody map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		log.Printf("Failed to decode request body: %v\n", err)
-		sendErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", nil)
+		logger.Warn("failed to decode request body", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeInvalidRequest, "Invalid request body"))
 		return
 	}
 
@@ -229,182 +491,467 @@ func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 	session := reqBody["session"]
 	credential := reqBody["credential"]
 
-	// Debug logging to understand what we're receiving
+	logger = logging.WithUseCase(logger, useCase)
 	sessionJSON, _ := json.Marshal(session)
-	credentialStr := ""
-	if credStr, ok := credential.(string); ok {
-		if len(credStr) > 100 {
-			credentialStr = credStr[:100] + "...[TRUNCATED]"
-		} else {
-			credentialStr = credStr
-		}
+	logger.Debug("process request received", "session_size_bytes", len(sessionJSON), "credential", credential)
+
+	// Validate required fields
+	if useCase == "" || session == nil || credential == nil {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeValidationError,
+			"use_case, session, and credential are required"))
+		return
 	}
 
-	if os.Getenv("GLIDE_LOG_FORMAT") != "pretty" {
-		log.Printf("/api/phone-auth/process - UseCase: %s\n", useCase)
-		log.Printf("Session received (size: %d bytes): %s\n", len(sessionJSON), string(sessionJSON))
-		log.Printf("Credential received: %s\n", credentialStr)
+	// A client-supplied Idempotency-Key lets a retried request (common on
+	// flaky mobile networks) replay the first response instead of calling
+	// GetPhoneNumber/VerifyPhoneNumber again, which could double-charge or
+	// return an inconsistent result for the same credential. Reserve claims
+	// the key for the in-flight request so a concurrent duplicate - the
+	// retry racing the original instead of following it - can't slip past
+	// the cache and re-execute the same upstream call.
+	var idempotencyKey string
+	idempotencyCompleted := false
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		candidateKey := ratelimit.ClientID(r) + ":" + key
+		if acquired, err := a.idempotency.Reserve(r.Context(), candidateKey, idempotencyReservationTTL); err != nil {
+			logger.Warn("failed to reserve idempotency key", "error", err)
+		} else if !acquired {
+			if cached, found, err := a.idempotency.Get(r.Context(), candidateKey); err != nil {
+				logger.Warn("failed to read idempotency cache", "error", err)
+			} else if found {
+				logger.Info("replaying cached response for idempotency key")
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(cached)
+				return
+			}
+			logger.Info("rejecting concurrent duplicate request for in-flight idempotency key")
+			apiutil.WriteError(w, r, apiutil.New(http.StatusConflict, apiutil.CodeIdempotencyInProgress, "A request with this Idempotency-Key is already in progress"))
+			return
+		} else {
+			idempotencyKey = candidateKey
+			defer func() {
+				if !idempotencyCompleted {
+					if relErr := a.idempotency.Release(context.Background(), idempotencyKey); relErr != nil {
+						logger.Warn("failed to release idempotency reservation", "error", relErr)
+					}
+				}
+			}()
+		}
 	}
 
-	// Validate required fields
-	if useCase == "" || session == nil || credential == nil {
-		sendErrorResponse(w, http.StatusBadRequest, "VALIDATION_ERROR",
-			"use_case, session, and credential are required", nil)
+	if allowed, retryAfter := a.breaker.Allow(); !allowed {
+		logger.Warn("circuit breaker open, short-circuiting process")
+		writeRetryAfter(w, retryAfter)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusServiceUnavailable, apiutil.CodeServiceUnavailable, "Upstream is currently unavailable, please retry shortly"))
 		return
 	}
 
-	ctx := context.Background()
+	ctx := r.Context()
 	var result interface{}
 	var err error
 
+	start := time.Now()
+
 	// Call the appropriate SDK method based on use_case
 	// The SDK now accepts the same structure the client sends
 	if useCase == "GetPhoneNumber" {
-		result, err = glideClient.MagicAuth.GetPhoneNumber(ctx, &glide.GetPhoneNumberRequest{
+		var span *tracing.Span
+		ctx, span = tracing.Start(ctx, "get_phone_number")
+		result, err = a.glideClient.MagicAuth.GetPhoneNumber(ctx, &glide.GetPhoneNumberRequest{
 			Session:    session,
 			Credential: credential,
 		})
+		span.End(logger)
 	} else if useCase == "VerifyPhoneNumber" {
-		result, err = glideClient.MagicAuth.VerifyPhoneNumber(ctx, &glide.VerifyPhoneNumberRequest{
+		var span *tracing.Span
+		ctx, span = tracing.Start(ctx, "verify_phone_number")
+		result, err = a.glideClient.MagicAuth.VerifyPhoneNumber(ctx, &glide.VerifyPhoneNumberRequest{
 			Session:    session,
 			Credential: credential,
 		})
+		span.End(logger)
 	} else {
-		sendErrorResponse(w, http.StatusBadRequest, "VALIDATION_ERROR",
-			fmt.Sprintf("Invalid use_case. Must be 'GetPhoneNumber' or 'VerifyPhoneNumber', got: %s", useCase), nil)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeValidationError,
+			fmt.Sprintf("Invalid use_case. Must be 'GetPhoneNumber' or 'VerifyPhoneNumber', got: %s", useCase)))
 		return
 	}
 
+	metrics.ProcessDuration.Observe(time.Since(start).Seconds(), useCase)
+
 	if err != nil {
-		handleGlideError(w, err)
+		a.breaker.RecordError()
+		apiutil.WriteError(w, r, problemFromGlideError(r, err))
 		return
 	}
+	a.breaker.RecordSuccess()
 
 	// Return the result as-is
+	body, err := json.Marshal(result)
+	if err != nil {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeUnexpectedError, "Failed to encode response"))
+		return
+	}
+
+	if idempotencyKey != "" {
+		if err := a.idempotency.Put(r.Context(), idempotencyKey, body, a.idempotencyTTL); err != nil {
+			logger.Warn("failed to store idempotency cache entry", "error", err)
+		} else {
+			idempotencyCompleted = true
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	w.Write(body)
 }
 
-func handleGlideError(w http.ResponseWriter, err error) {
-	if glideErr, ok := err.(*glide.Error); ok {
-		log.Printf("GlideError details: code=%s, message=%s, status=%d, requestId=%s\n",
-			glideErr.Code, glideErr.Message, glideErr.Status, glideErr.RequestID)
+// problemFromGlideError turns a *glide.Error into a problem response so
+// every handler funnels Glide failures through the same apiutil.WriteError
+// path. The SDK-defined code is passed through unchanged as the problem's
+// code; it is not translated to the codes declared in apiutil, and a code
+// with no title registered there uses the code string itself as its title.
+func problemFromGlideError(r *http.Request, err error) *apiutil.Problem {
+	logger := logging.FromContext(r.Context())
 
-		status := glideErr.Status
-		if status == 0 {
-			status = http.StatusInternalServerError
-		}
+	glideErr, ok := err.(*glide.Error)
+	if !ok {
+		logger.Error("unexpected error", "error", err)
+		return apiutil.New(http.StatusInternalServerError, apiutil.CodeUnexpectedError, "An unexpected error occurred")
+	}
 
-		// Include all error fields in details for proper error handling
-		allDetails := glideErr.Details
-		if allDetails == nil {
-			allDetails = make(map[string]interface{})
-		}
-		// Add requestID if it exists (not in details)
-		if glideErr.RequestID != "" {
-			allDetails["requestId"] = glideErr.RequestID
-		}
-		// Add status for client reference
-		allDetails["status"] = glideErr.Status
+	logger.Error("glide error", "code", glideErr.Code, "message", glideErr.Message,
+		"status", glideErr.Status, "requestId", glideErr.RequestID)
+	metrics.UpstreamErrorsTotal.Inc(string(glideErr.Code))
 
-		sendErrorResponse(w, status, string(glideErr.Code), glideErr.Message, allDetails)
-	} else {
-		log.Printf("Unexpected error: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "UNEXPECTED_ERROR", err.Error(), nil)
+	status := glideErr.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
 	}
+
+	details := glideErr.Details
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["status"] = glideErr.Status
+
+	return apiutil.New(status, apiutil.Code(glideErr.Code), glideErr.Message).
+		WithRequestID(glideErr.RequestID).
+		WithDetails(details)
 }
 
-func sendErrorResponse(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+func (a *App) phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
+
+	// Extract session ID from the path
+	// Path format: /api/phone-auth/status/{sessionId}
+	path := strings.TrimPrefix(r.URL.Path, "/api/phone-auth/status/")
+	sessionID := strings.TrimSpace(path)
 
-	response := ErrorResponse{
-		Error:   code,
-		Message: message,
+	if sessionID == "" {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeInvalidRequest, "Session ID is required"))
+		return
 	}
 
-	if details != nil {
-		response.Details = details
+	logger := logging.WithSession(logging.FromContext(r.Context()), sessionID)
+	logger.Debug("fetching status")
+
+	ctx, span := tracing.Start(r.Context(), "status_poll")
+	defer span.End(logger)
+
+	// Prefer the exact status_url captured from the prepare response; only
+	// fall back to guessing a public URL when we never saw one (e.g. the
+	// server restarted, or prepare was called against an old instance).
+	statusURL := a.apiBaseURL + "/public/public/status/" + sessionID
+	if entry, found, err := a.sessionStore.Get(ctx, sessionID); err != nil {
+		logger.Warn("error reading session store", "error", err)
+	} else if found {
+		statusURL = entry.StatusURL
 	}
 
-	// Add request ID if in details
-	if details != nil {
-		if reqID, ok := details["request_id"].(string); ok {
-			response.RequestID = reqID
-		} else if reqID, ok := details["requestId"].(string); ok {
-			response.RequestID = reqID
-		}
+	statusReq, err := http.NewRequestWithContext(ctx, "GET", statusURL, nil)
+	if err != nil {
+		logger.Error("error creating status request", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeRequestError, "Failed to create status request"))
+		return
 	}
 
-	// Add stack trace in development
-	if strings.ToLower(os.Getenv("NODE_ENV")) == "development" && details == nil {
-		response.Details = map[string]interface{}{
-			"env": "development",
+	statusReq.Header.Set("Accept", "application/json")
+	statusReq.Header.Set("traceparent", span.Traceparent())
+	if apiKey := os.Getenv("GLIDE_API_KEY"); apiKey != "" {
+		statusReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := a.httpClient.Do(statusReq)
+	if err != nil {
+		logger.Error("error fetching status", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeStatusCheckFailed, "Failed to check status"))
+		return
+	}
+	defer resp.Body.Close()
+
+	metrics.StatusPollTotal.Inc(strconv.Itoa(resp.StatusCode))
+	logger.Debug("status check returned", "status_code", resp.StatusCode)
+
+	// Buffer the body instead of streaming it straight through so we can
+	// peek at the status field below; callers still see the exact bytes,
+	// content type, and retry hints the Glide API sent.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Error("error reading status response", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeStatusCheckFailed, "Failed to read status response"))
+		return
+	}
+
+	var parsed struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && webhook.IsTerminalStatus(parsed.Status) {
+		if err := a.sessionStore.Delete(r.Context(), sessionID); err != nil {
+			logger.Warn("failed to delete resolved session", "error", err)
 		}
 	}
 
-	json.NewEncoder(w).Encode(response)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := w.Write(body); err != nil {
+		logger.Error("error writing status response", "error", err)
+	}
 }
 
-func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
+// phoneAuthStreamHandler upgrades to Server-Sent Events and pushes status
+// transitions for sessionID as the shared streaming.Hub poller observes
+// them, so the frontend can drop its status-polling loop in favor of a
+// single long-lived connection. Multiple subscribers for the same session
+// share one upstream poller; see streaming.Hub.
+func (a *App) phoneAuthStreamHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apiutil.MethodNotAllowed(w, r)
 		return
 	}
 
-	// Extract session ID from the path
-	// Path format: /api/phone-auth/status/{sessionId}
-	path := strings.TrimPrefix(r.URL.Path, "/api/phone-auth/status/")
+	path := strings.TrimPrefix(r.URL.Path, "/api/phone-auth/stream/")
 	sessionID := strings.TrimSpace(path)
-
 	if sessionID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Session ID is required", nil)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeInvalidRequest, "Session ID is required"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeUnexpectedError, "Streaming unsupported"))
 		return
 	}
 
-	log.Printf("[Status Proxy] Fetching status for session: %s\n", sessionID)
+	logger := logging.WithSession(logging.FromContext(r.Context()), sessionID)
+
+	statusURL := a.apiBaseURL + "/public/public/status/" + sessionID
+	if entry, found, err := a.sessionStore.Get(r.Context(), sessionID); err != nil {
+		logger.Warn("error reading session store", "error", err)
+	} else if found {
+		statusURL = entry.StatusURL
+	}
+
+	events, unsubscribe := a.streams.Subscribe(sessionID, statusURL, a.sessionTTL)
+	defer unsubscribe()
+
+	logger.Debug("status stream opened")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			logger.Debug("status stream closed by client")
+			return
+		case event, open := <-events:
+			if !open {
+				logger.Debug("status stream closed, watcher ended")
+				return
+			}
+			if event == nil {
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logger.Error("failed to marshal stream event", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+			if webhook.IsTerminalStatus(event.Status) {
+				return
+			}
+		}
+	}
+}
 
-	// Make request to the public status endpoint
-	statusURL := fmt.Sprintf("https://api.glideidentity.app/public/public/status/%s", sessionID)
+// registerWebhookHandler lets a customer subscribe to session-completion
+// events so they can learn a session resolved without polling
+// /api/phone-auth/status on a timer.
+func (a *App) registerWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
 
-	req, err := http.NewRequest("GET", statusURL, nil)
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusUnauthorized, apiutil.CodeUnauthenticated, "X-API-Key is required to register a webhook"))
+		return
+	}
+
+	var req struct {
+		URL    string `json:"url"`
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeInvalidRequest, "Invalid request body"))
+		return
+	}
+
+	if req.URL == "" || req.Secret == "" {
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeValidationError, "url and secret are required"))
+		return
+	}
+
+	logger := logging.FromContext(r.Context())
+	sub, err := a.webhooks.Subscribe(r.Context(), ratelimit.ClientID(r), req.URL, req.Secret)
+	if errors.Is(err, webhook.ErrInvalidEndpoint) {
+		logger.Warn("webhook registration rejected", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeValidationError, err.Error()))
+		return
+	}
 	if err != nil {
-		log.Printf("[Status Proxy] Error creating request: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "REQUEST_ERROR",
-			"Failed to create status request", nil)
+		logger.Error("failed to register webhook subscriber", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusInternalServerError, apiutil.CodeUnexpectedError, "Failed to register webhook"))
 		return
 	}
+	logger.Info("webhook subscriber registered", "subscriber_id", sub.ID, "url", sub.URL)
 
-	req.Header.Set("Accept", "application/json")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"id": sub.ID, "url": sub.URL})
+}
+
+// oauth2AuthorizeHandler is the OIDC authorization endpoint: it drives a
+// native phone-auth flow via oidc.Issuer and redirects the browser back to
+// redirect_uri with either a code or an error, per RFC 6749 section 4.1.
+// The request is held open until the user completes native auth, so it can
+// take up to the issuer's authorize timeout to respond.
+func (a *App) oauth2AuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	q := r.URL.Query()
+	req := oidc.AuthorizeRequest{
+		ResponseType:        q.Get("response_type"),
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+		PhoneNumber:         q.Get("login_hint"),
+	}
+
+	logger := logging.FromContext(r.Context())
+
+	redirectURL, err := a.oidcIssuer.Authorize(r.Context(), req)
 	if err != nil {
-		log.Printf("[Status Proxy] Error fetching status: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "STATUS_CHECK_FAILED",
-			"Failed to check status", nil)
+		logger.Warn("oauth2 authorize rejected", "error", err)
+		apiutil.WriteError(w, r, apiutil.New(http.StatusBadRequest, apiutil.CodeInvalidRequest, err.Error()))
 		return
 	}
-	defer resp.Body.Close()
 
-	log.Printf("[Status Proxy] Status check returned %d\n", resp.StatusCode)
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+// oauth2TokenHandler redeems a single-use authorization code for a signed
+// ID token. It follows RFC 6749 section 5.2's error shape rather than this
+// API's usual problem+json body, since OAuth2/OIDC client libraries expect
+// {"error": "..."} from the token endpoint.
+func (a *App) oauth2TokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
 
-	// Read the response body
-	var responseData interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
-		log.Printf("[Status Proxy] Error decoding response: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "DECODE_ERROR",
-			"Failed to decode status response", nil)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request")
 		return
 	}
 
-	log.Printf("[Status Proxy] Status response: %+v\n", responseData)
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
 
-	// Forward the response
-	if resp.StatusCode >= 400 {
-		w.WriteHeader(resp.StatusCode)
+	result, err := a.oidcIssuer.Token(
+		r.PostForm.Get("client_id"),
+		r.PostForm.Get("code"),
+		r.PostForm.Get("code_verifier"),
+		r.PostForm.Get("redirect_uri"),
+	)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("oauth2 token exchange failed", "error", err)
+		code := "invalid_grant"
+		if err.Error() == "invalid_client" {
+			code = "invalid_client"
+		}
+		writeOAuthError(w, http.StatusBadRequest, code)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(responseData)
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id_token":   result.IDToken,
+		"token_type": "Bearer",
+		"expires_in": result.ExpiresIn,
+	})
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// openIDConfigurationHandler serves OIDC discovery so stock client
+// libraries can locate the authorization, token, and JWKS endpoints
+// without hardcoding them.
+func (a *App) openIDConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.oidcIssuer.Discovery())
+}
+
+// jwksHandler serves the public half of the current (and, during a
+// rotation's grace window, previous) ID-token signing key.
+func (a *App) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiutil.MethodNotAllowed(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.oidcIssuer.JWKS())
 }
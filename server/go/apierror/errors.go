@@ -0,0 +1,190 @@
+package apierror
+
+import "net/http"
+
+// Typed constructors for the error conditions this API actually returns.
+// Handlers should build errors through these rather than apierror.Problem{}
+// literals, so the status code and RFC 7807 title for a given failure
+// mode stay consistent wherever it's raised.
+
+func ErrValidation(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Validation error",
+		Status: http.StatusBadRequest,
+		Detail: detail,
+		Code:   "VALIDATION_ERROR",
+	}
+}
+
+func ErrInvalidRequest(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Invalid request",
+		Status: http.StatusBadRequest,
+		Detail: detail,
+		Code:   "INVALID_REQUEST",
+	}
+}
+
+func ErrUnauthenticated(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Authentication failed",
+		Status: http.StatusUnauthorized,
+		Detail: detail,
+		Code:   "UNAUTHENTICATED",
+	}
+}
+
+func ErrSessionExpired(sessionID string) *Problem {
+	return (&Problem{
+		Type:   "about:blank",
+		Title:  "Session not found",
+		Status: http.StatusNotFound,
+		Detail: "Session not found. It may have expired or prepare was not called.",
+		Code:   "SESSION_NOT_FOUND",
+	}).WithSessionID(sessionID)
+}
+
+func ErrUpstreamCarrier(detail, carrierCode string) *Problem {
+	return (&Problem{
+		Type:   "about:blank",
+		Title:  "Upstream carrier error",
+		Status: http.StatusBadGateway,
+		Detail: detail,
+		Code:   "UPSTREAM_CARRIER_ERROR",
+	}).WithCarrierCode(carrierCode)
+}
+
+func ErrUpstream(status int, detail string) *Problem {
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Upstream Glide API error",
+		Status: status,
+		Detail: detail,
+		Code:   "UPSTREAM_ERROR",
+	}
+}
+
+func ErrRateLimited(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Too many requests",
+		Status: http.StatusTooManyRequests,
+		Detail: detail,
+		Code:   "RATE_LIMITED",
+	}
+}
+
+func ErrServiceUnavailable(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Service temporarily unavailable",
+		Status: http.StatusServiceUnavailable,
+		Detail: detail,
+		Code:   "SERVICE_UNAVAILABLE",
+	}
+}
+
+func ErrInternal(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Unexpected error",
+		Status: http.StatusInternalServerError,
+		Detail: detail,
+		Code:   "UNEXPECTED_ERROR",
+	}
+}
+
+func ErrSDKNotInitialized(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Glide SDK not initialized",
+		Status: http.StatusServiceUnavailable,
+		Detail: detail,
+		Code:   "SDK_NOT_INITIALIZED",
+	}
+}
+
+func ErrRequestFailed(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Failed to build upstream request",
+		Status: http.StatusInternalServerError,
+		Detail: detail,
+		Code:   "REQUEST_ERROR",
+	}
+}
+
+func ErrStatusCheckFailed(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Failed to check status",
+		Status: http.StatusInternalServerError,
+		Detail: detail,
+		Code:   "STATUS_CHECK_FAILED",
+	}
+}
+
+func ErrDecodeFailed(detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  "Failed to decode upstream response",
+		Status: http.StatusInternalServerError,
+		Detail: detail,
+		Code:   "DECODE_ERROR",
+	}
+}
+
+// carrierCodes are the Code values a *glide.MagicalAuthError carries when
+// the failure originated at the carrier rather than Glide itself; these
+// map to ErrUpstreamCarrier instead of the generic ErrUpstream fallback.
+var carrierCodes = map[string]bool{
+	"CARRIER_REJECTED":    true,
+	"CARRIER_TIMEOUT":     true,
+	"CARRIER_UNAVAILABLE": true,
+	"NUMBER_NOT_COVERED":  true,
+}
+
+// authCodes map to ErrUnauthenticated rather than a generic upstream error,
+// since the client can actually do something about them (fix credentials).
+var authCodes = map[string]bool{
+	"UNAUTHENTICATED":     true,
+	"INVALID_CREDENTIALS": true,
+	"INVALID_CLIENT":      true,
+	"TOKEN_EXPIRED":       true,
+}
+
+// FromGlideError maps a *glide.MagicalAuthError's SDK-defined Code to this
+// API's canonical taxonomy, so every call site funnels Glide failures
+// through the same lookup instead of re-deriving a status code per
+// handler. message and status are the error's own fields; requestID and
+// details, when non-empty, are attached to the resulting Problem.
+func FromGlideError(code, message string, status int, requestID string, details map[string]interface{}) *Problem {
+	var problem *Problem
+	switch {
+	case authCodes[code]:
+		problem = ErrUnauthenticated(message)
+	case carrierCodes[code]:
+		carrierCode, _ := details["carrierCode"].(string)
+		problem = ErrUpstreamCarrier(message, carrierCode)
+	case code == "RATE_LIMITED":
+		problem = ErrRateLimited(message)
+	case code == "SERVICE_UNAVAILABLE":
+		problem = ErrServiceUnavailable(message)
+	default:
+		problem = ErrUpstream(status, message)
+	}
+
+	if requestID != "" {
+		problem = problem.WithRequestID(requestID)
+	}
+	if len(details) > 0 {
+		problem = problem.WithDetails(details)
+	}
+	return problem
+}
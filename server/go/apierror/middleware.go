@@ -0,0 +1,21 @@
+package apierror
+
+import (
+	"log"
+	"net/http"
+)
+
+// Recover converts a panic anywhere in next into an ErrInternal problem
+// response instead of taking down the server, so handlers don't each need
+// their own recover().
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				log.Printf("panic handling %s %s: %v\n", r.Method, r.URL.Path, rec)
+				Write(w, r, ErrInternal("An unexpected error occurred"))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
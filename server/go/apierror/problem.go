@@ -0,0 +1,89 @@
+// Package apierror gives this server a single, typed error taxonomy and
+// renders every failure as an RFC 7807 (application/problem+json) body,
+// so handlers stop hand-rolling status codes and ad-hoc JSON shapes.
+package apierror
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Problem is an RFC 7807 problem-details body, with a handful of
+// module-specific extension members this API relies on.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Code        string                 `json:"code"`
+	RequestID   string                 `json:"requestId,omitempty"`
+	SessionID   string                 `json:"sessionId,omitempty"`
+	CarrierCode string                 `json:"carrierCode,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error lets a Problem be returned and handled like any other Go error.
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// WithRequestID attaches a request ID and returns the Problem for chaining.
+func (p *Problem) WithRequestID(requestID string) *Problem {
+	p.RequestID = requestID
+	return p
+}
+
+// WithSessionID attaches the session this error occurred against.
+func (p *Problem) WithSessionID(sessionID string) *Problem {
+	p.SessionID = sessionID
+	return p
+}
+
+// WithCarrierCode attaches the upstream carrier's own error code, when the
+// failure came back from a carrier rather than from Glide or this server.
+func (p *Problem) WithCarrierCode(carrierCode string) *Problem {
+	p.CarrierCode = carrierCode
+	return p
+}
+
+// WithDetails sets the extra module-specific fields reported under the
+// problem's "details" member, replacing any set before.
+func (p *Problem) WithDetails(details map[string]interface{}) *Problem {
+	p.Details = details
+	return p
+}
+
+// Write renders err as a problem-details response. Any error that isn't a
+// *Problem is reported as ErrInternal without leaking its message to the
+// client.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	problem, ok := err.(*Problem)
+	if !ok {
+		log.Printf("apierror: non-Problem error reported: %v\n", err)
+		problem = ErrInternal("internal error")
+	}
+	if problem.Instance == "" {
+		problem.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// MethodNotAllowed writes a 405 problem response.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, &Problem{
+		Type:   "about:blank",
+		Title:  "Method not allowed",
+		Status: http.StatusMethodNotAllowed,
+		Detail: "Method " + r.Method + " is not allowed on this endpoint",
+		Code:   "METHOD_NOT_ALLOWED",
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"strings"
 
+	"magical-auth-quickstart-go/apierror"
+
 	glide "github.com/GlideIdentity/glide-be-sdk-go/v2"
 	"github.com/joho/godotenv"
 	"github.com/rs/cors"
@@ -25,13 +27,6 @@ type HealthCheckResponse struct {
 	} `json:"env"`
 }
 
-type ErrorResponse struct {
-	Error     string                 `json:"error"`
-	Message   string                 `json:"message"`
-	RequestID string                 `json:"requestId,omitempty"`
-	Details   map[string]interface{} `json:"details,omitempty"`
-}
-
 func main() {
 	// Load environment variables from root .env file
 	// Try root level first (when run via npm scripts), then current dir (for direct execution)
@@ -89,7 +84,7 @@ func main() {
 		Debug:          false,
 	})
 
-	handler := c.Handler(mux)
+	handler := apierror.Recover(c.Handler(mux))
 
 	log.Printf("Server running on http://localhost:%s\n", port)
 
@@ -100,7 +95,7 @@ func main() {
 
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r)
 		return
 	}
 
@@ -122,19 +117,18 @@ func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
 func phoneAuthPrepareHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r)
 		return
 	}
 
 	if glideClient == nil {
-		sendErrorResponse(w, http.StatusServiceUnavailable, "SDK_NOT_INITIALIZED",
-			"Glide SDK not initialized. Check your credentials.", nil)
+		apierror.Write(w, r, apierror.ErrSDKNotInitialized("Glide SDK not initialized. Check your credentials."))
 		return
 	}
 
 	var req glide.PrepareRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", nil)
+		apierror.Write(w, r, apierror.ErrInvalidRequest("Invalid request body"))
 		return
 	}
 
@@ -155,7 +149,7 @@ func phoneAuthPrepareHandler(w http.ResponseWriter, r *http.Request) {
 
 	response, err := glideClient.MagicalAuth.Prepare(ctx, &req)
 	if err != nil {
-		handleGlideError(w, err)
+		apierror.Write(w, r, problemFromGlideError(err))
 		return
 	}
 
@@ -175,7 +169,7 @@ func phoneAuthPrepareHandler(w http.ResponseWriter, r *http.Request) {
 // This call can be made asynchronously without blocking the flow.
 func phoneAuthInvokeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r)
 		return
 	}
 
@@ -229,13 +223,12 @@ func phoneAuthInvokeHandler(w http.ResponseWriter, r *http.Request) {
 
 func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r)
 		return
 	}
 
 	if glideClient == nil {
-		sendErrorResponse(w, http.StatusServiceUnavailable, "SDK_NOT_INITIALIZED",
-			"Glide SDK not initialized. Check your credentials.", nil)
+		apierror.Write(w, r, apierror.ErrSDKNotInitialized("Glide SDK not initialized. Check your credentials."))
 		return
 	}
 
@@ -247,7 +240,7 @@ func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
 		log.Printf("Failed to decode request body: %v\n", err)
-		sendErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body", nil)
+		apierror.Write(w, r, apierror.ErrInvalidRequest("Invalid request body"))
 		return
 	}
 
@@ -255,8 +248,7 @@ func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Validate required fields
 	if reqBody.UseCase == "" || reqBody.Credential == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "VALIDATION_ERROR",
-			"use_case, session, and credential are required", nil)
+		apierror.Write(w, r, apierror.ErrValidation("use_case, session, and credential are required"))
 		return
 	}
 
@@ -289,13 +281,13 @@ func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 		result = response
 		err = e
 	default:
-		sendErrorResponse(w, http.StatusBadRequest, "VALIDATION_ERROR",
-			fmt.Sprintf("Invalid use_case. Must be 'GetPhoneNumber' or 'VerifyPhoneNumber', got: %s", reqBody.UseCase), nil)
+		apierror.Write(w, r, apierror.ErrValidation(
+			fmt.Sprintf("Invalid use_case. Must be 'GetPhoneNumber' or 'VerifyPhoneNumber', got: %s", reqBody.UseCase)))
 		return
 	}
 
 	if err != nil {
-		handleGlideError(w, err)
+		apierror.Write(w, r, problemFromGlideError(err))
 		return
 	}
 
@@ -303,57 +295,32 @@ func phoneAuthProcessHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
-func handleGlideError(w http.ResponseWriter, err error) {
-	if glideErr, ok := err.(*glide.MagicalAuthError); ok {
-		log.Printf("âŒ MagicalAuthError: code=%s, message=%s, status=%d\n",
-			glideErr.Code, glideErr.Message, glideErr.Status)
-
-		status := glideErr.Status
-		if status == 0 {
-			status = http.StatusInternalServerError
-		}
-
-		// Include all error fields in details
-		allDetails := glideErr.Details
-		if allDetails == nil {
-			allDetails = make(map[string]interface{})
-		}
-		if glideErr.RequestID != "" {
-			allDetails["requestId"] = glideErr.RequestID
-		}
-		allDetails["status"] = glideErr.Status
-
-		sendErrorResponse(w, status, glideErr.Code, glideErr.Message, allDetails)
-	} else {
+// problemFromGlideError maps a *glide.MagicalAuthError's SDK-defined code
+// to this API's canonical taxonomy via apierror.FromGlideError, so every
+// call site funnels Glide failures through the same lookup table instead
+// of re-deriving a status code per handler.
+func problemFromGlideError(err error) *apierror.Problem {
+	glideErr, ok := err.(*glide.MagicalAuthError)
+	if !ok {
 		log.Printf("âŒ Unexpected error: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "UNEXPECTED_ERROR", err.Error(), nil)
+		return apierror.ErrInternal("internal error")
 	}
-}
-
-func sendErrorResponse(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
 
-	response := ErrorResponse{
-		Error:   code,
-		Message: message,
-	}
+	log.Printf("âŒ MagicalAuthError: code=%s, message=%s, status=%d\n",
+		glideErr.Code, glideErr.Message, glideErr.Status)
 
-	if details != nil {
-		response.Details = details
-		if reqID, ok := details["request_id"].(string); ok {
-			response.RequestID = reqID
-		} else if reqID, ok := details["requestId"].(string); ok {
-			response.RequestID = reqID
-		}
+	details := glideErr.Details
+	if details == nil {
+		details = make(map[string]interface{})
 	}
+	details["status"] = glideErr.Status
 
-	json.NewEncoder(w).Encode(response)
+	return apierror.FromGlideError(glideErr.Code, glideErr.Message, glideErr.Status, glideErr.RequestID, details)
 }
 
 func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r)
 		return
 	}
 
@@ -362,7 +329,7 @@ func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	sessionID := strings.TrimSpace(path)
 
 	if sessionID == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "INVALID_REQUEST", "Session ID is required", nil)
+		apierror.Write(w, r, apierror.ErrInvalidRequest("Session ID is required"))
 		return
 	}
 
@@ -374,8 +341,7 @@ func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 			sessionPreview = sessionPreview[:8] + "..."
 		}
 		log.Printf("[Status Proxy] No stored status URL for session: %s\n", sessionPreview)
-		sendErrorResponse(w, http.StatusNotFound, "SESSION_NOT_FOUND",
-			"Session not found. It may have expired or prepare was not called.", nil)
+		apierror.Write(w, r, apierror.ErrSessionExpired(sessionID))
 		return
 	}
 
@@ -389,8 +355,7 @@ func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	req, err := http.NewRequest("GET", statusURL, nil)
 	if err != nil {
 		log.Printf("[Status Proxy] Error creating request: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "REQUEST_ERROR",
-			"Failed to create status request", nil)
+		apierror.Write(w, r, apierror.ErrRequestFailed("Failed to create status request"))
 		return
 	}
 
@@ -399,8 +364,7 @@ func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("[Status Proxy] Error fetching status: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "STATUS_CHECK_FAILED",
-			"Failed to check status", nil)
+		apierror.Write(w, r, apierror.ErrStatusCheckFailed("Failed to check status"))
 		return
 	}
 	defer resp.Body.Close()
@@ -411,8 +375,7 @@ func phoneAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	var responseData interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&responseData); err != nil {
 		log.Printf("[Status Proxy] Error decoding response: %v\n", err)
-		sendErrorResponse(w, http.StatusInternalServerError, "DECODE_ERROR",
-			"Failed to decode status response", nil)
+		apierror.Write(w, r, apierror.ErrDecodeFailed("Failed to decode status response"))
 		return
 	}
 
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	glide "github.com/GlideIdentity/glide-be-sdk-go"
+)
+
+// sessionEntry is the value stored against a session key. It carries
+// enough of the prepare response for the status proxy and webhook
+// dispatcher to operate without re-deriving it (use case for event
+// payloads, PLMN for diagnostics, CreatedAt for observability) rather than
+// just the status URL a process-local map used to hold.
+type sessionEntry struct {
+	StatusURL string
+	UseCase   string
+	PLMN      *glide.PLMN
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SessionStore abstracts where prepare-response metadata lives so the
+// quickstart can run behind a load balancer without losing sessions when
+// requests land on a different instance than the one that handled prepare.
+type SessionStore interface {
+	Put(ctx context.Context, key string, entry sessionEntry, ttl time.Duration) error
+	Get(ctx context.Context, key string) (sessionEntry, bool, error)
+	Delete(ctx context.Context, key string) error
+	// Count reports the number of live sessions, for the glide_active_sessions gauge.
+	Count(ctx context.Context) (int, error)
+}
+
+// NewSessionStore builds the SessionStore selected by the SESSION_STORE env
+// var ("memory", "redis", "sql", or "postgres"), defaulting to "memory"
+// when unset. Persistent backends are wrapped in a fallback store so a
+// backend outage degrades to in-memory sessions instead of failing prepare
+// and status requests outright.
+func NewSessionStore(backend string) (SessionStore, error) {
+	switch backend {
+	case "", "memory":
+		return newMemorySessionStore(), nil
+	case "redis":
+		store, err := newRedisSessionStoreFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return newFallbackSessionStore(store), nil
+	case "sql", "postgres":
+		store, err := newSQLSessionStoreFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return newFallbackSessionStore(store), nil
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE backend %q", backend)
+	}
+}
+
+// memorySessionStore is the original process-local implementation, kept as
+// the zero-dependency default for local development.
+type memorySessionStore struct {
+	mu      sync.RWMutex
+	entries map[string]sessionEntry
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	s := &memorySessionStore{entries: make(map[string]sessionEntry)}
+	go s.cleanupLoop()
+	return s
+}
+
+func (s *memorySessionStore) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	for range ticker.C {
+		s.mu.Lock()
+		now := time.Now()
+		for key, entry := range s.entries {
+			if entry.ExpiresAt.Before(now) {
+				delete(s.entries, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *memorySessionStore) Put(_ context.Context, key string, entry sessionEntry, ttl time.Duration) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.ExpiresAt = time.Now().Add(ttl)
+	s.mu.Lock()
+	s.entries[key] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySessionStore) Get(_ context.Context, key string) (sessionEntry, bool, error) {
+	s.mu.RLock()
+	entry, ok := s.entries[key]
+	s.mu.RUnlock()
+	if !ok || entry.ExpiresAt.Before(time.Now()) {
+		return sessionEntry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+func (s *memorySessionStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySessionStore) Count(_ context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	count := 0
+	for _, entry := range s.entries {
+		if !entry.ExpiresAt.Before(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// fallbackSessionStore wraps a persistent SessionStore with an in-memory
+// one. When the primary backend errors (connection refused, timeout, a
+// Redis/Postgres blip) it logs nothing itself and serves the request out
+// of the memory store instead, so a transient outage degrades to
+// process-local sessions rather than breaking prepare and status checks.
+// Put and Delete still return the primary's error for the caller to log;
+// Get returns the fallback's result and drops the primary's error.
+type fallbackSessionStore struct {
+	primary  SessionStore
+	fallback *memorySessionStore
+}
+
+func newFallbackSessionStore(primary SessionStore) *fallbackSessionStore {
+	return &fallbackSessionStore{primary: primary, fallback: newMemorySessionStore()}
+}
+
+func (s *fallbackSessionStore) Put(ctx context.Context, key string, entry sessionEntry, ttl time.Duration) error {
+	if err := s.primary.Put(ctx, key, entry, ttl); err != nil {
+		_ = s.fallback.Put(ctx, key, entry, ttl)
+		return err
+	}
+	return nil
+}
+
+func (s *fallbackSessionStore) Get(ctx context.Context, key string) (sessionEntry, bool, error) {
+	entry, found, err := s.primary.Get(ctx, key)
+	if err != nil {
+		return s.fallback.Get(ctx, key)
+	}
+	return entry, found, nil
+}
+
+func (s *fallbackSessionStore) Delete(ctx context.Context, key string) error {
+	err := s.primary.Delete(ctx, key)
+	_ = s.fallback.Delete(ctx, key)
+	return err
+}
+
+func (s *fallbackSessionStore) Count(ctx context.Context) (int, error) {
+	if count, err := s.primary.Count(ctx); err == nil {
+		return count, nil
+	}
+	return s.fallback.Count(ctx)
+}
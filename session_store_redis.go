@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisSessionStore stores session entries as JSON strings under
+// "SET key value EX ttl" and relies on Redis's own expiry instead of a
+// background sweep goroutine.
+type redisSessionStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisSessionStoreFromEnv() (*redisSessionStore, error) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return nil, errors.New("REDIS_ADDR must be set when SESSION_STORE=redis")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       0,
+	})
+
+	prefix := os.Getenv("REDIS_KEY_PREFIX")
+	if prefix == "" {
+		prefix = "glide:session:"
+	}
+
+	return &redisSessionStore{client: client, prefix: prefix}, nil
+}
+
+func (s *redisSessionStore) key(sessionKey string) string {
+	return s.prefix + sessionKey
+}
+
+func (s *redisSessionStore) Put(ctx context.Context, key string, entry sessionEntry, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.key(key), data, ttl).Err()
+}
+
+func (s *redisSessionStore) Get(ctx context.Context, key string) (sessionEntry, bool, error) {
+	data, err := s.client.Get(ctx, s.key(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return sessionEntry{}, false, nil
+	}
+	if err != nil {
+		return sessionEntry{}, false, err
+	}
+
+	var entry sessionEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return sessionEntry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (s *redisSessionStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.key(key)).Err()
+}
+
+// Count scans for string keys under this store's prefix; the type filter
+// keeps the webhook subscriber hash, stored under the same prefix, out of
+// the count. It's an approximation (SCAN is not a point-in-time snapshot)
+// but good enough for a gauge.
+func (s *redisSessionStore) Count(ctx context.Context) (int, error) {
+	var count int
+	var cursor uint64
+	for {
+		keys, next, err := s.client.ScanType(ctx, cursor, s.prefix+"*", 100, "string").Result()
+		if err != nil {
+			return 0, err
+		}
+		count += len(keys)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"time"
+
+	glide "github.com/GlideIdentity/glide-be-sdk-go"
+	_ "github.com/lib/pq"
+)
+
+// sqlSessionStore persists session entries in a Postgres table (via
+// database/sql and lib/pq) and sweeps expired rows on a timer, since most
+// SQL engines have no built-in TTL. It backs both SESSION_STORE=sql and
+// SESSION_STORE=postgres.
+type sqlSessionStore struct {
+	db *sql.DB
+}
+
+func newSQLSessionStoreFromEnv() (*sqlSessionStore, error) {
+	dsn := os.Getenv("SESSION_STORE_DSN")
+	if dsn == "" {
+		return nil, errors.New("SESSION_STORE_DSN must be set when SESSION_STORE=sql or SESSION_STORE=postgres")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS glide_sessions (
+			session_key TEXT PRIMARY KEY,
+			status_url  TEXT NOT NULL,
+			use_case    TEXT NOT NULL DEFAULT '',
+			plmn_mcc    TEXT,
+			plmn_mnc    TEXT,
+			created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			expires_at  TIMESTAMPTZ NOT NULL
+		)
+	`); err != nil {
+		return nil, err
+	}
+
+	store := &sqlSessionStore{db: db}
+	go store.sweepLoop()
+	return store, nil
+}
+
+func (s *sqlSessionStore) sweepLoop() {
+	ticker := time.NewTicker(time.Minute)
+	for range ticker.C {
+		_, _ = s.db.Exec(`DELETE FROM glide_sessions WHERE expires_at < now()`)
+	}
+}
+
+func (s *sqlSessionStore) Put(ctx context.Context, key string, entry sessionEntry, ttl time.Duration) error {
+	createdAt := entry.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	var mcc, mnc sql.NullString
+	if entry.PLMN != nil {
+		mcc = sql.NullString{String: entry.PLMN.MCC, Valid: true}
+		mnc = sql.NullString{String: entry.PLMN.MNC, Valid: true}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO glide_sessions (session_key, status_url, use_case, plmn_mcc, plmn_mnc, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (session_key) DO UPDATE SET
+			status_url = $2, use_case = $3, plmn_mcc = $4, plmn_mnc = $5, expires_at = $7
+	`, key, entry.StatusURL, entry.UseCase, mcc, mnc, createdAt, expiresAt)
+	return err
+}
+
+func (s *sqlSessionStore) Get(ctx context.Context, key string) (sessionEntry, bool, error) {
+	var entry sessionEntry
+	var mcc, mnc sql.NullString
+	row := s.db.QueryRowContext(ctx, `
+		SELECT status_url, use_case, plmn_mcc, plmn_mnc, created_at, expires_at
+		FROM glide_sessions WHERE session_key = $1
+	`, key)
+
+	if err := row.Scan(&entry.StatusURL, &entry.UseCase, &mcc, &mnc, &entry.CreatedAt, &entry.ExpiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sessionEntry{}, false, nil
+		}
+		return sessionEntry{}, false, err
+	}
+
+	if mcc.Valid || mnc.Valid {
+		entry.PLMN = &glide.PLMN{MCC: mcc.String, MNC: mnc.String}
+	}
+
+	if entry.ExpiresAt.Before(time.Now()) {
+		return sessionEntry{}, false, nil
+	}
+	return entry, true, nil
+}
+
+func (s *sqlSessionStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM glide_sessions WHERE session_key = $1`, key)
+	return err
+}
+
+func (s *sqlSessionStore) Count(ctx context.Context) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM glide_sessions WHERE expires_at >= now()`).Scan(&count)
+	return count, err
+}
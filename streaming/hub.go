@@ -0,0 +1,205 @@
+// Package streaming pushes phone-auth session status transitions to
+// subscribers (SSE today, a WebSocket upgrade is a natural follow-up)
+// instead of requiring the browser to re-poll /api/phone-auth/status on a
+// timer.
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"magical-auth-quickstart-go/webhook"
+)
+
+// pollMinInterval is the poll interval a watcher starts at and resets to
+// after a successful fetch; pollMaxInterval caps the exponential backoff
+// applied after consecutive fetch errors.
+const (
+	pollMinInterval   = 2 * time.Second
+	pollMaxInterval   = 30 * time.Second
+	heartbeatInterval = 15 * time.Second
+)
+
+// Event is a status transition pushed to stream subscribers.
+type Event struct {
+	SessionKey string      `json:"session_key"`
+	Status     string      `json:"status"`
+	Result     interface{} `json:"result,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// Hub dedups concurrent subscribers to the same session behind a single
+// upstream poller, so N browser tabs watching one sessionID cost one
+// goroutine and one set of requests to the Glide status endpoint instead
+// of N.
+type Hub struct {
+	httpClient *http.Client
+	apiKey     string
+	logger     *slog.Logger
+
+	mu       sync.Mutex
+	watchers map[string]*watcher
+}
+
+// NewHub builds a Hub that polls statusURLs with httpClient, authenticating
+// with apiKey the same way phoneAuthStatusHandler does.
+func NewHub(httpClient *http.Client, apiKey string, logger *slog.Logger) *Hub {
+	return &Hub{httpClient: httpClient, apiKey: apiKey, logger: logger, watchers: make(map[string]*watcher)}
+}
+
+// watcher polls a single session's statusURL and fans events out to every
+// subscriber registered while it's running. A nil Event on the channel is
+// a heartbeat ping, not a status transition.
+type watcher struct {
+	sessionKey  string
+	mu          sync.Mutex
+	subscribers map[chan *Event]struct{}
+	cancel      context.CancelFunc
+}
+
+// Subscribe registers the caller as a listener for sessionKey's status
+// transitions, starting the shared poller if this is the first subscriber
+// for that session. The returned channel delivers a *Event per status
+// change, nil for heartbeats, and closes when the session reaches a
+// terminal state or ttl elapses. Calling the returned unsubscribe func
+// stops delivery to the channel without closing it.
+// ttl bounds the watcher's total lifetime, mirroring the TTL the session
+// entry itself is stored under.
+func (h *Hub) Subscribe(sessionKey, statusURL string, ttl time.Duration) (events <-chan *Event, unsubscribe func()) {
+	h.mu.Lock()
+	w, exists := h.watchers[sessionKey]
+	if !exists {
+		ctx, cancel := context.WithTimeout(context.Background(), ttl)
+		w = &watcher{
+			sessionKey:  sessionKey,
+			subscribers: make(map[chan *Event]struct{}),
+			cancel:      cancel,
+		}
+		h.watchers[sessionKey] = w
+		go h.run(ctx, w, statusURL)
+	}
+
+	ch := make(chan *Event, 4)
+	w.mu.Lock()
+	w.subscribers[ch] = struct{}{}
+	w.mu.Unlock()
+	h.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		delete(w.subscribers, ch)
+		remaining := len(w.subscribers)
+		w.mu.Unlock()
+		if remaining == 0 {
+			w.cancel()
+		}
+	}
+}
+
+// run polls statusURL until ctx is done or a terminal status is observed,
+// broadcasting every transition (and periodic heartbeats) to w's
+// subscribers, then tears the watcher down so a later Subscribe call for
+// the same session starts a fresh poller.
+func (h *Hub) run(ctx context.Context, w *watcher, statusURL string) {
+	defer h.forget(w)
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	interval := pollMinInterval
+	poll := time.NewTimer(interval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.broadcast(&Event{SessionKey: w.sessionKey, Status: "EXPIRED", OccurredAt: time.Now()})
+			w.closeAll()
+			return
+		case <-heartbeat.C:
+			w.broadcast(nil)
+		case <-poll.C:
+			status, result, terminal, err := h.fetchStatus(ctx, statusURL)
+			if err != nil {
+				h.logger.Warn("stream status poll failed", "session_id", w.sessionKey, "error", err)
+				interval = nextBackoff(interval)
+				poll.Reset(interval)
+				continue
+			}
+			interval = pollMinInterval
+			w.broadcast(&Event{SessionKey: w.sessionKey, Status: status, Result: result, OccurredAt: time.Now()})
+			if terminal {
+				w.closeAll()
+				return
+			}
+			poll.Reset(interval)
+		}
+	}
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > pollMaxInterval {
+		return pollMaxInterval
+	}
+	return d
+}
+
+func (h *Hub) forget(w *watcher) {
+	h.mu.Lock()
+	if h.watchers[w.sessionKey] == w {
+		delete(h.watchers, w.sessionKey)
+	}
+	h.mu.Unlock()
+}
+
+func (w *watcher) broadcast(e *Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// Subscriber isn't draining fast enough; drop rather than
+			// block the shared poller over one slow client.
+		}
+	}
+}
+
+func (w *watcher) closeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.subscribers {
+		close(ch)
+	}
+	w.subscribers = make(map[chan *Event]struct{})
+}
+
+func (h *Hub) fetchStatus(ctx context.Context, statusURL string) (status string, result map[string]interface{}, terminal bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return "", nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", nil, false, err
+	}
+
+	statusValue, _ := body["status"].(string)
+	return statusValue, body, webhook.IsTerminalStatus(statusValue), nil
+}
@@ -0,0 +1,114 @@
+// Package tracing instruments the auth pipeline with spans that
+// propagate as a W3C Trace Context (RFC-editor draft, commonly just
+// "traceparent") header, so a request can be followed from this server
+// into the upstream Glide status-URL fetch.
+//
+// This hand-rolls span creation and traceparent propagation instead of
+// depending on go.opentelemetry.io/otel: this sandbox has no module
+// proxy access, so a new third-party dependency can't be added with a
+// verifiable go.sum entry. Spans are logged structurally (trace ID, span
+// ID, parent span ID, name, duration) rather than exported over OTLP;
+// OTLPEndpoint below is wired through from config so a real OTLP exporter
+// can be dropped in without touching call sites once that dependency is
+// available.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// OTLPEndpoint is the configured OTLP collector endpoint, read from
+// OTEL_EXPORTER_OTLP_ENDPOINT. It's currently unused by the logging-only
+// exporter below but is surfaced so wiring in a real exporter later is a
+// one-line change rather than a new plumbing path.
+var OTLPEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+
+type ctxKey struct{}
+
+// Span is one traced operation. Zero value is not usable; create one with
+// Start.
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	start        time.Time
+	attrs        map[string]any
+}
+
+// Start begins a new span named name, becoming a child of whatever span is
+// already in ctx (if any), and returns the context carrying it.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent, _ := ctx.Value(ctxKey{}).(*Span)
+
+	span := &Span{
+		TraceID: traceIDFrom(parent),
+		SpanID:  randomHex(8),
+		Name:    name,
+		start:   time.Now(),
+		attrs:   make(map[string]any),
+	}
+	if parent != nil {
+		span.ParentSpanID = parent.SpanID
+	}
+
+	return context.WithValue(ctx, ctxKey{}, span), span
+}
+
+// FromContext returns the span in ctx, if any.
+func FromContext(ctx context.Context) (*Span, bool) {
+	span, ok := ctx.Value(ctxKey{}).(*Span)
+	return span, ok
+}
+
+// SetAttr attaches a key/value pair reported alongside the span on End.
+func (s *Span) SetAttr(key string, value any) {
+	s.attrs[key] = value
+}
+
+// End logs the span's duration and attributes. Callers typically
+// `defer span.End(logger)` right after Start.
+func (s *Span) End(logger *slog.Logger) {
+	args := []any{
+		"trace_id", s.TraceID,
+		"span_id", s.SpanID,
+		"duration_ms", time.Since(s.start).Milliseconds(),
+	}
+	if s.ParentSpanID != "" {
+		args = append(args, "parent_span_id", s.ParentSpanID)
+	}
+	for k, v := range s.attrs {
+		args = append(args, k, v)
+	}
+	logger.Debug("span "+s.Name, args...)
+}
+
+// Traceparent renders s as a W3C traceparent header value, so it can be
+// forwarded to an upstream request (e.g. the status-URL fetch) and that
+// request's own span can be linked back to s.
+func (s *Span) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+func traceIDFrom(parent *Span) string {
+	if parent != nil {
+		return parent.TraceID
+	}
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand failing is effectively unrecoverable; fall back to an
+		// all-zero ID rather than panicking a request over a tracing detail.
+		return hex.EncodeToString(buf)
+	}
+	return hex.EncodeToString(buf)
+}
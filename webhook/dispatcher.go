@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnauthenticatedSubscriber is returned by Subscribe when no client
+// identity was supplied, since every subscription must be scoped to the
+// client that registered it.
+var ErrUnauthenticatedSubscriber = errors.New("webhook subscription requires an authenticated client")
+
+// pollInterval is how often the dispatcher checks an in-flight session's
+// status URL while waiting for a terminal result.
+const pollInterval = 2 * time.Second
+
+// maxAttempts is the total number of delivery attempts (the first attempt
+// plus retries) before a failing delivery is dead-lettered.
+const maxAttempts = 5
+
+// retryDelays is the backoff between delivery attempts.
+var retryDelays = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second, 5 * time.Minute}
+
+// ReplayWindow is how old a delivery's X-Glide-Signature timestamp may be
+// before a receiver should treat the request as a replay and reject it.
+const ReplayWindow = 5 * time.Minute
+
+// terminalStatuses are the upstream status values that stop polling and
+// trigger delivery.
+var terminalStatuses = map[string]bool{
+	"COMPLETED": true,
+	"SUCCESS":   true,
+	"FAILED":    true,
+	"ERROR":     true,
+	"EXPIRED":   true,
+	"AUTH_OK":   true,
+	"AUTH_FAIL": true,
+}
+
+// IsTerminalStatus reports whether status (case-insensitive) is one of the
+// values that end polling, so callers outside this package - such as the
+// status proxy handler - can decide to tear down session state the moment
+// the upstream session resolves rather than waiting for its TTL to lapse.
+func IsTerminalStatus(status string) bool {
+	return terminalStatuses[strings.ToUpper(status)]
+}
+
+// SessionEvent is the payload POSTed to subscribers once a session reaches
+// a terminal state. ClientID scopes delivery to the subscribers owned by
+// the client that prepared the session and is never sent on the wire.
+type SessionEvent struct {
+	SessionKey string      `json:"session_key"`
+	UseCase    string      `json:"use_case"`
+	Status     string      `json:"status"`
+	Result     interface{} `json:"result"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	ClientID   string      `json:"-"`
+}
+
+// Dispatcher polls an upstream status URL until a session resolves, then
+// delivers the result to every registered subscriber with retries.
+type Dispatcher struct {
+	subscribers    SubscriberStore
+	httpClient     *http.Client
+	deliveryClient *http.Client
+	apiKey         string
+	logger         *slog.Logger
+}
+
+// NewDispatcher builds a Dispatcher backed by the given subscriber store.
+// apiKey is sent as a bearer token when polling the upstream status URL,
+// matching how phoneAuthStatusHandler authenticates the same call.
+// Deliveries to subscriber endpoints go out over a separate client (see
+// newDeliveryClient) that re-validates the destination IP on every
+// attempt, since httpClient itself is also used to poll trusted upstream
+// URLs and shouldn't have its dialer restricted.
+func NewDispatcher(subscribers SubscriberStore, httpClient *http.Client, apiKey string, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		subscribers:    subscribers,
+		httpClient:     httpClient,
+		deliveryClient: newDeliveryClient(httpClient.Timeout),
+		apiKey:         apiKey,
+		logger:         logger,
+	}
+}
+
+// Subscribe registers a new webhook endpoint under clientID and returns
+// the stored subscriber record, including its generated ID. clientID (see
+// ratelimit.ClientID) must be non-empty: an unauthenticated caller has no
+// client identity to scope deliveries to, and accepting the subscription
+// anyway would hand it every session's events regardless of who owns them.
+func (d *Dispatcher) Subscribe(ctx context.Context, clientID, url, secret string) (Subscriber, error) {
+	if clientID == "" {
+		return Subscriber{}, ErrUnauthenticatedSubscriber
+	}
+	if err := ValidateEndpointURL(url); err != nil {
+		return Subscriber{}, err
+	}
+
+	sub := Subscriber{ID: newSubscriberID(), ClientID: clientID, URL: url, Secret: secret}
+	if err := d.subscribers.Add(ctx, sub); err != nil {
+		return Subscriber{}, err
+	}
+	return sub, nil
+}
+
+// StartPolling begins polling statusURL in the background, bounded by ttl
+// (the same TTL the session's status URL is stored under), and delivers a
+// SessionEvent to clientID's subscribers once the session reaches a
+// terminal state. If ttl elapses first, the session is reported as
+// "EXPIRED" so subscribers aren't left waiting on a session that will
+// never resolve. clientID is the identity that prepared the session (see
+// ratelimit.ClientID); only that client's own subscribers are notified.
+func (d *Dispatcher) StartPolling(sessionKey, useCase, statusURL, clientID string, ttl time.Duration) {
+	subscribers, err := d.subscribers.List(context.Background(), clientID)
+	if err != nil {
+		d.logger.Warn("failed to check webhook subscribers, skipping poll", "session_id", sessionKey, "error", err)
+		return
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ttl)
+		defer cancel()
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				d.deliver(context.Background(), SessionEvent{
+					SessionKey: sessionKey,
+					UseCase:    useCase,
+					Status:     "EXPIRED",
+					OccurredAt: time.Now(),
+					ClientID:   clientID,
+				})
+				return
+			case <-ticker.C:
+				status, result, terminal, err := d.fetchStatus(ctx, statusURL)
+				if err != nil {
+					d.logger.Warn("webhook status poll failed", "session_id", sessionKey, "error", err)
+					continue
+				}
+				if !terminal {
+					continue
+				}
+				d.deliver(context.Background(), SessionEvent{
+					SessionKey: sessionKey,
+					UseCase:    useCase,
+					Status:     status,
+					Result:     result,
+					OccurredAt: time.Now(),
+					ClientID:   clientID,
+				})
+				return
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) fetchStatus(ctx context.Context, statusURL string) (status string, result map[string]interface{}, terminal bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return "", nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if d.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+d.apiKey)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", nil, false, err
+	}
+
+	statusValue, _ := body["status"].(string)
+	return statusValue, body, terminalStatuses[strings.ToUpper(statusValue)], nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event SessionEvent) {
+	subscribers, err := d.subscribers.List(ctx, event.ClientID)
+	if err != nil {
+		d.logger.Error("failed to list webhook subscribers", "error", err)
+		return
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("failed to marshal webhook event", "session_id", event.SessionKey, "error", err)
+		return
+	}
+
+	for _, sub := range subscribers {
+		go d.deliverWithRetry(sub, body)
+	}
+}
+
+// deliverWithRetry POSTs body to sub with exponential backoff, logging a
+// dead-letter entry once maxAttempts is exhausted.
+func (d *Dispatcher) deliverWithRetry(sub Subscriber, body []byte) {
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(retryDelays[attempt-2])
+		}
+
+		if err := d.attemptDelivery(sub, body); err != nil {
+			lastErr = err
+			d.logger.Warn("webhook delivery attempt failed", "subscriber_id", sub.ID, "attempt", attempt, "error", err)
+			continue
+		}
+		return
+	}
+
+	d.logger.Error("webhook delivery exhausted retries, dead-lettering", "subscriber_id", sub.ID, "url", sub.URL, "attempts", maxAttempts, "error", lastErr)
+}
+
+func (d *Dispatcher) attemptDelivery(sub Subscriber, body []byte) error {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Glide-Signature", sign(sub.Secret, timestamp, body))
+
+	resp, err := d.deliveryClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Package webhook delivers terminal MagicAuth session results to
+// customer-registered endpoints instead of requiring the frontend to poll
+// /api/phone-auth/status on a timer.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// Subscriber is a customer-registered webhook endpoint. Secret is only
+// ever persisted internally (it's what signs outbound deliveries) -
+// callers building an HTTP response must not echo it back. ClientID is
+// the identity (see ratelimit.ClientID) that registered the subscription;
+// List only ever returns a client's own subscribers, so one customer
+// can't receive another's session events.
+type Subscriber struct {
+	ID       string `json:"id"`
+	ClientID string `json:"client_id"`
+	URL      string `json:"url"`
+	Secret   string `json:"secret"`
+}
+
+// SubscriberStore abstracts where registered webhook endpoints live,
+// mirroring SessionStore's pluggable-backend design so subscribers survive
+// restarts and are visible across instances behind a load balancer.
+type SubscriberStore interface {
+	Add(ctx context.Context, sub Subscriber) error
+	// List returns the subscribers registered under clientID, never another
+	// client's.
+	List(ctx context.Context, clientID string) ([]Subscriber, error)
+}
+
+// NewSubscriberStore builds the SubscriberStore selected by the
+// SESSION_STORE env var ("memory", "redis", "sql", or "postgres"), matching
+// the backend NewSessionStore picks for the same env var.
+func NewSubscriberStore(backend string) (SubscriberStore, error) {
+	switch backend {
+	case "", "memory":
+		return newMemorySubscriberStore(), nil
+	case "redis":
+		return newRedisSubscriberStoreFromEnv()
+	case "sql", "postgres":
+		return newSQLSubscriberStoreFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE backend %q", backend)
+	}
+}
+
+func newSubscriberID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "sub_unknown"
+	}
+	return "sub_" + hex.EncodeToString(buf)
+}
+
+// memorySubscriberStore is the zero-dependency default for local
+// development, mirroring memorySessionStore.
+type memorySubscriberStore struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+func newMemorySubscriberStore() *memorySubscriberStore {
+	return &memorySubscriberStore{}
+}
+
+func (s *memorySubscriberStore) Add(_ context.Context, sub Subscriber) error {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, sub)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySubscriberStore) List(_ context.Context, clientID string) ([]Subscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Subscriber, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		if sub.ClientID == clientID {
+			out = append(out, sub)
+		}
+	}
+	return out, nil
+}
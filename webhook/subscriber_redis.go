@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisSubscriberStore stores every subscriber as a field in a single Redis
+// hash, since the full subscriber list (unlike session entries) has no
+// natural per-key TTL and is small enough to fetch in one round trip.
+type redisSubscriberStore struct {
+	client *redis.Client
+	key    string
+}
+
+func newRedisSubscriberStoreFromEnv() (*redisSubscriberStore, error) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		return nil, errors.New("REDIS_ADDR must be set when SESSION_STORE=redis")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       0,
+	})
+
+	prefix := os.Getenv("REDIS_KEY_PREFIX")
+	if prefix == "" {
+		prefix = "glide:session:"
+	}
+
+	return &redisSubscriberStore{client: client, key: prefix + "webhook_subscribers"}, nil
+}
+
+func (s *redisSubscriberStore) Add(ctx context.Context, sub Subscriber) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, s.key, sub.ID, data).Err()
+}
+
+func (s *redisSubscriberStore) List(ctx context.Context, clientID string) ([]Subscriber, error) {
+	values, err := s.client.HVals(ctx, s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip individual entries that fail to decode rather than failing the
+	// whole list, so one malformed subscriber doesn't stop delivery to
+	// every other customer's endpoint.
+	subscribers := make([]Subscriber, 0, len(values))
+	for _, v := range values {
+		var sub Subscriber
+		if err := json.Unmarshal([]byte(v), &sub); err != nil {
+			continue
+		}
+		if sub.ClientID != clientID {
+			continue
+		}
+		subscribers = append(subscribers, sub)
+	}
+	return subscribers, nil
+}
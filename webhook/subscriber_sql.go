@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+
+	_ "github.com/lib/pq"
+)
+
+// sqlSubscriberStore persists subscribers in a SQL table, mirroring
+// sqlSessionStore's lazy schema creation.
+type sqlSubscriberStore struct {
+	db *sql.DB
+}
+
+func newSQLSubscriberStoreFromEnv() (*sqlSubscriberStore, error) {
+	dsn := os.Getenv("SESSION_STORE_DSN")
+	if dsn == "" {
+		return nil, errors.New("SESSION_STORE_DSN must be set when SESSION_STORE=sql or SESSION_STORE=postgres")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS glide_webhook_subscribers (
+			id        TEXT PRIMARY KEY,
+			client_id TEXT NOT NULL,
+			url       TEXT NOT NULL,
+			secret    TEXT NOT NULL
+		)
+	`); err != nil {
+		return nil, err
+	}
+
+	return &sqlSubscriberStore{db: db}, nil
+}
+
+func (s *sqlSubscriberStore) Add(ctx context.Context, sub Subscriber) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO glide_webhook_subscribers (id, client_id, url, secret) VALUES ($1, $2, $3, $4)
+	`, sub.ID, sub.ClientID, sub.URL, sub.Secret)
+	return err
+}
+
+func (s *sqlSubscriberStore) List(ctx context.Context, clientID string) ([]Subscriber, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, client_id, url, secret FROM glide_webhook_subscribers WHERE client_id = $1
+	`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []Subscriber
+	for rows.Next() {
+		var sub Subscriber
+		if err := rows.Scan(&sub.ID, &sub.ClientID, &sub.URL, &sub.Secret); err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, sub)
+	}
+	return subscribers, rows.Err()
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrInvalidEndpoint wraps ValidateEndpointURL failures so callers (like
+// the HTTP handler registering a subscriber) can tell a bad request apart
+// from an internal storage error.
+var ErrInvalidEndpoint = errors.New("invalid webhook endpoint")
+
+// ValidateEndpointURL rejects webhook URLs that would let a subscriber
+// point the dispatcher's outbound requests at internal infrastructure
+// (loopback, link-local/cloud-metadata, or other private address ranges)
+// since the dispatcher makes the request from inside our network, not the
+// registering caller's.
+func ValidateEndpointURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEndpoint, err)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("%w: URL must use https", ErrInvalidEndpoint)
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("%w: URL must include a host", ErrInvalidEndpoint)
+	}
+
+	ips, err := net.LookupIP(parsed.Hostname())
+	if err != nil {
+		// Resolution failures are surfaced at delivery time instead, since a
+		// hostname that doesn't resolve yet may still be valid by then.
+		return nil
+	}
+	for _, ip := range ips {
+		if isDisallowedTarget(ip) {
+			return fmt.Errorf("%w: URL resolves to a disallowed address %s", ErrInvalidEndpoint, ip)
+		}
+	}
+	return nil
+}
+
+func isDisallowedTarget(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
+
+// newDeliveryClient builds the http.Client attemptDelivery uses to POST
+// events to subscriber endpoints. ValidateEndpointURL only runs at
+// subscribe time, so a subscriber could pass it with a public address and
+// then repoint DNS at an internal one before delivery fires; this client's
+// DialContext re-resolves the host and re-checks the IP it actually
+// connects to immediately before dialing, closing that window.
+func newDeliveryClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = safeDialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// safeDialContext resolves addr's host, rejects it outright if nothing
+// permitted comes back, and dials the allowed IPs directly (not the
+// hostname), in the order resolved, until one connects, so the connection
+// can't land on a disallowed address no matter what a subsequent DNS
+// lookup by net/http's own dialer would have returned.
+func safeDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var lastErr error
+	for _, ip := range ips {
+		if isDisallowedTarget(ip) {
+			lastErr = fmt.Errorf("%w: URL resolves to a disallowed address %s", ErrInvalidEndpoint, ip)
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("%w: %s did not resolve to any address", ErrInvalidEndpoint, host)
+	}
+	return nil, lastErr
+}